@@ -1,25 +1,66 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"runtime"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	addblockhandler "go-runtime-demo/internal/app/blockchain/handler/addblock"
+	blockstreamhandler "go-runtime-demo/internal/app/blockchain/handler/blockstream"
+	chainexporthandler "go-runtime-demo/internal/app/blockchain/handler/chainexport"
+	chainimporthandler "go-runtime-demo/internal/app/blockchain/handler/chainimport"
+	chaininfohandler "go-runtime-demo/internal/app/blockchain/handler/chaininfo"
+	chaintipshandler "go-runtime-demo/internal/app/blockchain/handler/chaintips"
+	getaccounthandler "go-runtime-demo/internal/app/blockchain/handler/getaccount"
+	getblockhandler "go-runtime-demo/internal/app/blockchain/handler/getblock"
+	getblockbyhashhandler "go-runtime-demo/internal/app/blockchain/handler/getblockbyhash"
+	getmempoolhandler "go-runtime-demo/internal/app/blockchain/handler/getmempool"
 	listblockshandler "go-runtime-demo/internal/app/blockchain/handler/listblocks"
 	mineparallelhandler "go-runtime-demo/internal/app/blockchain/handler/mineparallel"
 	stresstesthandler "go-runtime-demo/internal/app/blockchain/handler/stresstest"
+	submitblockhandler "go-runtime-demo/internal/app/blockchain/handler/submitblock"
+	submittransactionhandler "go-runtime-demo/internal/app/blockchain/handler/submittransaction"
+	txproofhandler "go-runtime-demo/internal/app/blockchain/handler/txproof"
+	validatechainhandler "go-runtime-demo/internal/app/blockchain/handler/validatechain"
 	gcbenchmarkhandler "go-runtime-demo/internal/app/monitoring/handler/gcbenchmark"
 	gcfinalizershandler "go-runtime-demo/internal/app/monitoring/handler/gcfinalizers"
 	gcmetricshandler "go-runtime-demo/internal/app/monitoring/handler/gcmetrics"
 	gcprofilehandler "go-runtime-demo/internal/app/monitoring/handler/gcprofile"
 	statshandler "go-runtime-demo/internal/app/monitoring/handler/stats"
+	blockrangehandler "go-runtime-demo/internal/app/p2p/handler/blockrange"
+	receiveblockhandler "go-runtime-demo/internal/app/p2p/handler/receiveblock"
+	p2pstatushandler "go-runtime-demo/internal/app/p2p/handler/status"
+	runsimulationhandler "go-runtime-demo/internal/app/simulation/handler/runsimulation"
+	createwallethandler "go-runtime-demo/internal/app/wallet/handler/createwallet"
+	signtransactionhandler "go-runtime-demo/internal/app/wallet/handler/signtransaction"
 
 	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
 	addblockusecase "go-runtime-demo/internal/app/blockchain/usecase/addblock"
+	blockstreamusecase "go-runtime-demo/internal/app/blockchain/usecase/blockstream"
+	chainexportusecase "go-runtime-demo/internal/app/blockchain/usecase/chainexport"
+	chainimportusecase "go-runtime-demo/internal/app/blockchain/usecase/chainimport"
+	chaininfousecase "go-runtime-demo/internal/app/blockchain/usecase/chaininfo"
+	chaintipsusecase "go-runtime-demo/internal/app/blockchain/usecase/chaintips"
+	getaccountusecase "go-runtime-demo/internal/app/blockchain/usecase/getaccount"
+	getblockusecase "go-runtime-demo/internal/app/blockchain/usecase/getblock"
+	getblockbyhashusecase "go-runtime-demo/internal/app/blockchain/usecase/getblockbyhash"
+	getmempoolusecase "go-runtime-demo/internal/app/blockchain/usecase/getmempool"
 	listblocksusecase "go-runtime-demo/internal/app/blockchain/usecase/listblocks"
 	mineparallelusecase "go-runtime-demo/internal/app/blockchain/usecase/mineparallel"
+	streamblocksusecase "go-runtime-demo/internal/app/blockchain/usecase/streamblocks"
 	stresstestusecase "go-runtime-demo/internal/app/blockchain/usecase/stresstest"
+	submitblockusecase "go-runtime-demo/internal/app/blockchain/usecase/submitblock"
+	submittransactionusecase "go-runtime-demo/internal/app/blockchain/usecase/submittransaction"
+	txproofusecase "go-runtime-demo/internal/app/blockchain/usecase/txproof"
+	validatechainusecase "go-runtime-demo/internal/app/blockchain/usecase/validatechain"
 	gcbenchmarkusecase "go-runtime-demo/internal/app/monitoring/usecase/gcbenchmark"
 	gcfinalizersusecase "go-runtime-demo/internal/app/monitoring/usecase/gcfinalizers"
 	gcmetricsusecase "go-runtime-demo/internal/app/monitoring/usecase/gcmetrics"
@@ -28,22 +69,116 @@ import (
 	monitoringdomain "go-runtime-demo/internal/app/monitoring/domain"
 	statsusecase "go-runtime-demo/internal/app/monitoring/usecase/stats"
 
+	p2pdomain "go-runtime-demo/internal/app/p2p/domain"
+	blockrangeusecase "go-runtime-demo/internal/app/p2p/usecase/blockrange"
+	receiveblockusecase "go-runtime-demo/internal/app/p2p/usecase/receiveblock"
+	p2pstatususecase "go-runtime-demo/internal/app/p2p/usecase/status"
+
+	runsimulationusecase "go-runtime-demo/internal/app/simulation/usecase/runsimulation"
+
+	walletdomain "go-runtime-demo/internal/app/wallet/domain"
+	createwalletusecase "go-runtime-demo/internal/app/wallet/usecase/createwallet"
+	signtransactionusecase "go-runtime-demo/internal/app/wallet/usecase/signtransaction"
+
 	httpserver "go-runtime-demo/pkg/http"
 )
 
+type config struct {
+	difficulty      int
+	difficultyMode  string
+	hashAlgorithm   string
+	retargetBlocks  int
+	targetBlockTime time.Duration
+	store           string
+	dataDir         string
+	syncMode        string
+	syncInterval    time.Duration
+	minerAddress    string
+	blockReward     uint64
+	mempoolSize     int
+	maxBlockTxs     int
+	autoMine        bool
+	port            string
+	nodeURL         string
+	peers           string
+	peerSync        time.Duration
+	maxPeers        int
+}
+
 func main() {
+	cfg := parseFlags()
+
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
 	printSchedulerInfo()
 
-	blockchain := blockchaindomain.NewBlockchain(4)
+	difficultyMode, err := blockchaindomain.ParseDifficultyMode(cfg.difficultyMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	hashAlgorithm, err := blockchaindomain.ParseHashAlgorithm(cfg.hashAlgorithm)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := newBlockStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blockchain, err := blockchaindomain.NewBlockchain(cfg.difficulty,
+		blockchaindomain.WithStore(store),
+		blockchaindomain.WithDifficultyMode(difficultyMode),
+		blockchaindomain.WithHashAlgorithm(hashAlgorithm),
+		blockchaindomain.WithBlockReward(cfg.blockReward),
+		blockchaindomain.WithMiner(cfg.minerAddress),
+		blockchaindomain.WithMempool(cfg.mempoolSize),
+		blockchaindomain.WithMaxBlockTransactions(cfg.maxBlockTxs),
+		blockchaindomain.WithRetarget(blockchaindomain.RetargetPolicy{
+			Interval:        cfg.retargetBlocks,
+			TargetBlockTime: cfg.targetBlockTime,
+		}),
+	)
+	if err != nil {
+		_ = store.Close()
+		log.Fatal(err)
+	}
+	log.Printf("Blockchain loaded from %s store with %d blocks (%s)", cfg.store, blockchain.Length(), blockchain.Params().Hasher.Algorithm())
+
 	monitor := monitoringdomain.NewMonitor()
+	keystore := walletdomain.NewKeystore()
+
+	node, err := p2pdomain.NewNode(blockchain, p2pdomain.Config{
+		URL:          cfg.nodeURL,
+		Peers:        splitList(cfg.peers),
+		SyncInterval: cfg.peerSync,
+		MaxPeers:     cfg.maxPeers,
+	})
+	if err != nil {
+		_ = blockchain.Close()
+		log.Fatal(err)
+	}
 
 	// Blockchain use cases
 	addBlockUC := addblockusecase.New(blockchain)
+	blockStreamUC := blockstreamusecase.New(blockchain)
+	chainExportUC := chainexportusecase.New(blockchain)
+	chainImportUC := chainimportusecase.New(blockchain)
+	chainInfoUC := chaininfousecase.New(blockchain)
+	chainTipsUC := chaintipsusecase.New(blockchain)
+	getAccountUC := getaccountusecase.New(blockchain)
+	getBlockUC := getblockusecase.New(blockchain)
+	getBlockByHashUC := getblockbyhashusecase.New(blockchain)
+	getMempoolUC := getmempoolusecase.New(blockchain)
 	listBlocksUC := listblocksusecase.New(blockchain)
 	mineParallelUC := mineparallelusecase.New(blockchain)
+	streamBlocksUC := streamblocksusecase.New(blockchain)
 	stressTestUC := stresstestusecase.New()
+	submitBlockUC := submitblockusecase.New(blockchain)
+	submitTransactionUC := submittransactionusecase.New(blockchain)
+	txProofUC := txproofusecase.New(blockchain)
+	validateChainUC := validatechainusecase.New(blockchain)
 
 	// Monitoring use cases
 	statsUC := statsusecase.New(monitor)
@@ -52,25 +187,71 @@ func main() {
 	gcMetricsUC := gcmetricsusecase.New()
 	gcProfileUC := gcprofileusecase.New()
 
+	// Wallet use cases
+	createWalletUC := createwalletusecase.New(keystore)
+	signTransactionUC := signtransactionusecase.New(keystore, blockchain)
+
+	// P2P use cases
+	p2pStatusUC := p2pstatususecase.New(node)
+	blockRangeUC := blockrangeusecase.New(node)
+	receiveBlockUC := receiveblockusecase.New(node)
+
+	// Simulation use cases
+	runSimulationUC := runsimulationusecase.New()
+
 	// Handlers
 	addBlockHandler := addblockhandler.NewHandler(addBlockUC)
-	listBlocksHandler := listblockshandler.NewHandler(listBlocksUC)
+	blockStreamHandler := blockstreamhandler.NewHandler(blockStreamUC)
+	chainExportHandler := chainexporthandler.NewHandler(chainExportUC)
+	chainImportHandler := chainimporthandler.NewHandler(chainImportUC)
+	chainInfoHandler := chaininfohandler.NewHandler(chainInfoUC)
+	chainTipsHandler := chaintipshandler.NewHandler(chainTipsUC)
+	getAccountHandler := getaccounthandler.NewHandler(getAccountUC)
+	getBlockHandler := getblockhandler.NewHandler(getBlockUC)
+	getBlockByHashHandler := getblockbyhashhandler.NewHandler(getBlockByHashUC)
+	getMempoolHandler := getmempoolhandler.NewHandler(getMempoolUC)
+	listBlocksHandler := listblockshandler.NewHandler(listBlocksUC, streamBlocksUC)
 	mineParallelHandler := mineparallelhandler.NewHandler(mineParallelUC)
 	stressTestHandler := stresstesthandler.NewHandler(stressTestUC)
+	submitBlockHandler := submitblockhandler.NewHandler(submitBlockUC)
+	submitTransactionHandler := submittransactionhandler.NewHandler(submitTransactionUC)
+	txProofHandler := txproofhandler.NewHandler(txProofUC)
+	validateChainHandler := validatechainhandler.NewHandler(validateChainUC)
 	statsHandler := statshandler.NewHandler(statsUC)
 	gcBenchmarkHandler := gcbenchmarkhandler.NewHandler(gcBenchmarkUC)
 	gcFinalizersHandler := gcfinalizershandler.NewHandler(gcFinalizersUC)
 	gcMetricsHandler := gcmetricshandler.NewHandler(gcMetricsUC)
 	gcProfileHandler := gcprofilehandler.NewHandler(gcProfileUC)
+	createWalletHandler := createwallethandler.NewHandler(createWalletUC)
+	signTransactionHandler := signtransactionhandler.NewHandler(signTransactionUC)
+	p2pStatusHandler := p2pstatushandler.NewHandler(p2pStatusUC)
+	blockRangeHandler := blockrangehandler.NewHandler(blockRangeUC)
+	receiveBlockHandler := receiveblockhandler.NewHandler(receiveBlockUC)
+	runSimulationHandler := runsimulationhandler.NewHandler(runSimulationUC)
 
-	server := httpserver.NewServer("8080")
+	server := httpserver.NewServer(cfg.port)
 	router := server.Router()
+	// Event streams only end when their subscription does
+	server.OnShutdown(blockchain.Events().Close)
 
 	// Blockchain endpoints
 	addblockhandler.RegisterEndpoint(router, addBlockHandler)
+	blockstreamhandler.RegisterEndpoint(router, blockStreamHandler)
+	chainexporthandler.RegisterEndpoint(router, chainExportHandler)
+	chainimporthandler.RegisterEndpoint(router, chainImportHandler)
+	chaininfohandler.RegisterEndpoint(router, chainInfoHandler)
+	chaintipshandler.RegisterEndpoint(router, chainTipsHandler)
+	getaccounthandler.RegisterEndpoint(router, getAccountHandler)
+	getblockhandler.RegisterEndpoint(router, getBlockHandler)
+	getblockbyhashhandler.RegisterEndpoint(router, getBlockByHashHandler)
+	getmempoolhandler.RegisterEndpoint(router, getMempoolHandler)
 	listblockshandler.RegisterEndpoint(router, listBlocksHandler)
 	mineparallelhandler.RegisterEndpoint(router, mineParallelHandler)
 	stresstesthandler.RegisterEndpoint(router, stressTestHandler)
+	submitblockhandler.RegisterEndpoint(router, submitBlockHandler)
+	submittransactionhandler.RegisterEndpoint(router, submitTransactionHandler)
+	txproofhandler.RegisterEndpoint(router, txProofHandler)
+	validatechainhandler.RegisterEndpoint(router, validateChainHandler)
 
 	// Monitoring endpoints
 	statshandler.RegisterEndpoint(router, statsHandler)
@@ -79,9 +260,126 @@ func main() {
 	gcmetricshandler.RegisterEndpoint(router, gcMetricsHandler)
 	gcprofilehandler.RegisterEndpoint(router, gcProfileHandler)
 
+	// Wallet endpoints
+	createwallethandler.RegisterEndpoint(router, createWalletHandler)
+	signtransactionhandler.RegisterEndpoint(router, signTransactionHandler)
+
+	// P2P endpoints
+	p2pstatushandler.RegisterEndpoint(router, p2pStatusHandler)
+	blockrangehandler.RegisterEndpoint(router, blockRangeHandler)
+	receiveblockhandler.RegisterEndpoint(router, receiveBlockHandler)
+
+	// Simulation endpoints
+	runsimulationhandler.RegisterEndpoint(router, runSimulationHandler)
+
+	// The miner and the peer goroutines have returned before the block store
+	// is closed, so none of them appends to a closed store
+	backgroundCtx, cancelBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	if cfg.autoMine {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			blockchain.MinePending(backgroundCtx)
+		}()
+	}
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		node.Run(backgroundCtx)
+	}()
+	stopBackground := func() {
+		cancelBackground()
+		background.Wait()
+	}
+
+	go shutdownOnSignal(server)
+
 	if err := server.Start(); err != nil {
+		stopBackground()
+		_ = blockchain.Close()
 		log.Fatal(err)
 	}
+	stopBackground()
+
+	if err := blockchain.Close(); err != nil {
+		log.Printf("closing block store: %v", err)
+	}
+}
+
+func parseFlags() config {
+	var cfg config
+
+	flag.IntVar(&cfg.difficulty, "difficulty", 4, "initial proof-of-work difficulty in -difficulty-mode units")
+	flag.StringVar(&cfg.difficultyMode, "difficulty-mode", string(blockchaindomain.DifficultyHex), "difficulty unit: hex (leading zero hex characters) or bits (leading zero bits)")
+	flag.StringVar(&cfg.hashAlgorithm, "hash", string(blockchaindomain.DefaultHashAlgorithm), "proof-of-work hash of a new chain: sha256, sha512/256, sha3-256 or double-sha256 (a stored chain keeps its own)")
+	flag.IntVar(&cfg.retargetBlocks, "retarget-interval", 0, "retarget difficulty every N blocks (0 keeps it fixed)")
+	flag.DurationVar(&cfg.targetBlockTime, "target-block-time", 2*time.Second, "block time the retarget aims for")
+	flag.StringVar(&cfg.store, "store", "memory", "block store: memory or file")
+	flag.StringVar(&cfg.dataDir, "data-dir", "data", "directory for the file block store")
+	flag.StringVar(&cfg.syncMode, "fsync", string(blockchaindomain.SyncAlways), "file store fsync mode: always, interval or never")
+	flag.DurationVar(&cfg.syncInterval, "fsync-interval", time.Second, "fsync period when -fsync=interval")
+	flag.StringVar(&cfg.minerAddress, "miner-address", "", "address credited with the reward of every mined block (requests may override it)")
+	flag.Uint64Var(&cfg.blockReward, "block-reward", blockchaindomain.DefaultBlockReward, "amount minted by the coinbase of each block")
+	flag.IntVar(&cfg.mempoolSize, "mempool-size", blockchaindomain.DefaultMempoolCapacity, "maximum number of pending transactions")
+	flag.IntVar(&cfg.maxBlockTxs, "max-block-txs", blockchaindomain.DefaultMaxBlockTransactions, "maximum pending transactions taken by a block")
+	flag.BoolVar(&cfg.autoMine, "auto-mine", false, "mine pending transactions in a background goroutine as they arrive")
+	flag.StringVar(&cfg.port, "port", "8080", "HTTP port to listen on")
+	flag.StringVar(&cfg.nodeURL, "node-url", "", "URL peers reach this node at (defaults to http://localhost:<port>)")
+	flag.StringVar(&cfg.peers, "peers", "", "comma-separated URLs of the peers to sync with, e.g. http://localhost:8081")
+	flag.DurationVar(&cfg.peerSync, "peer-sync-interval", p2pdomain.DefaultSyncInterval, "how often peers are polled for blocks we are missing")
+	flag.IntVar(&cfg.maxPeers, "max-peers", p2pdomain.DefaultMaxPeers, "maximum number of peers, configured ones included")
+	flag.Parse()
+
+	if cfg.nodeURL == "" {
+		cfg.nodeURL = "http://localhost:" + cfg.port
+	}
+
+	return cfg
+}
+
+// splitList splits a comma-separated flag value, ignoring empty entries
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func newBlockStore(cfg config) (blockchaindomain.BlockStore, error) {
+	switch cfg.store {
+	case "memory":
+		return blockchaindomain.NewMemoryStore(), nil
+	case "file":
+		syncMode, err := blockchaindomain.ParseSyncMode(cfg.syncMode)
+		if err != nil {
+			return nil, err
+		}
+		return blockchaindomain.OpenFileStore(blockchaindomain.FileStoreOptions{
+			Dir:          cfg.dataDir,
+			SyncMode:     syncMode,
+			SyncInterval: cfg.syncInterval,
+		})
+	default:
+		return nil, fmt.Errorf("unknown store %q (expected memory or file)", cfg.store)
+	}
+}
+
+// shutdownOnSignal stops the server on SIGINT/SIGTERM so main can close the block store
+func shutdownOnSignal(server *httpserver.Server) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
 }
 
 func printSchedulerInfo() {
@@ -1,48 +1,215 @@
 package domain
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
-	"runtime"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 )
 
+var ErrBlockNotFound = errors.New("block not found")
+
+// GenesisTime is the timestamp of every genesis block. Nodes started with the
+// same difficulty flags therefore share their genesis and can sync with each other.
+var GenesisTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 type (
 	Block struct {
-		Index        int       `json:"index"`
-		Timestamp    time.Time `json:"timestamp"`
-		Data         string    `json:"data"`
-		PreviousHash string    `json:"previous_hash"`
-		Hash         string    `json:"hash"`
-		Nonce        int       `json:"nonce"`
+		Index        int           `json:"index"`
+		Timestamp    time.Time     `json:"timestamp"`
+		Data         string        `json:"data"`
+		PreviousHash Hash          `json:"previous_hash"`
+		Hash         Hash          `json:"hash"`
+		Nonce        int           `json:"nonce"`
+		Difficulty   int           `json:"difficulty"`
+		MerkleRoot   OptionalHash  `json:"merkle_root,omitzero"`
+		Transactions []Transaction `json:"transactions,omitempty"`
+		Version      uint8         `json:"version"`
+		// HashAlgorithm is set by the genesis block only, and records the
+		// hasher of the whole chain
+		HashAlgorithm HashAlgorithm `json:"hash_algorithm,omitempty"`
 	}
 
+	// Blockchain keeps every known block in a tree of branches; chain is the
+	// canonical branch, the one with the most cumulative work, and ledger
+	// and mempool follow it
 	Blockchain struct {
-		chain      []Block
-		difficulty int
-		mu         sync.RWMutex
+		chain       []Block
+		nodes       map[Hash]*blockNode
+		tip         *blockNode
+		reorgs      int
+		params      Params
+		store       BlockStore
+		ledger      *Ledger
+		side        sideLedger
+		mempool     *Mempool
+		maxBlockTxs int
+		miner       string
+		listeners   []func(Block)
+		events      *Broadcaster
+		// hashAlgorithm is the WithHashAlgorithm choice, used for a new chain
+		hashAlgorithm HashAlgorithm
+		mu            sync.RWMutex
 	}
+
+	Option func(*Blockchain)
 )
 
-func NewBlockchain(difficulty int) *Blockchain {
+// WithRetarget enables difficulty retargeting. Defaults to a fixed difficulty.
+func WithRetarget(policy RetargetPolicy) Option {
+	return func(bc *Blockchain) {
+		bc.params.Retarget = policy
+	}
+}
+
+// WithDifficultyMode selects the unit difficulty is expressed in. Defaults to DifficultyHex.
+func WithDifficultyMode(mode DifficultyMode) Option {
+	return func(bc *Blockchain) {
+		bc.params.Mode = mode
+	}
+}
+
+// WithHashAlgorithm selects the proof-of-work digest of a new chain. A stored
+// chain keeps the algorithm recorded by its genesis block. Defaults to
+// DefaultHashAlgorithm.
+func WithHashAlgorithm(algorithm HashAlgorithm) Option {
+	return func(bc *Blockchain) {
+		bc.hashAlgorithm = algorithm
+	}
+}
+
+// WithBlockReward sets the amount minted by each coinbase. Defaults to DefaultBlockReward.
+func WithBlockReward(reward uint64) Option {
+	return func(bc *Blockchain) {
+		bc.params.BlockReward = reward
+	}
+}
+
+// WithMiner credits the block reward of every mined block to address unless
+// the payload names its own miner. Without a miner, blocks have no coinbase.
+func WithMiner(address string) Option {
+	return func(bc *Blockchain) {
+		bc.miner = address
+	}
+}
+
+// WithMempool bounds the number of pending transactions. Defaults to DefaultMempoolCapacity.
+func WithMempool(capacity int) Option {
+	return func(bc *Blockchain) {
+		bc.mempool = newMempool(capacity)
+	}
+}
+
+// WithMaxBlockTransactions bounds how many pending transactions a block
+// takes from the mempool. Defaults to DefaultMaxBlockTransactions.
+func WithMaxBlockTransactions(n int) Option {
+	return func(bc *Blockchain) {
+		bc.maxBlockTxs = n
+	}
+}
+
+// WithStore sets where blocks are persisted. Defaults to a MemoryStore.
+func WithStore(store BlockStore) Option {
+	return func(bc *Blockchain) {
+		bc.store = store
+	}
+}
+
+// NewBlockchain replays and validates the blocks held by the store, creating
+// and persisting a genesis block when the store is empty. difficulty is the
+// number of leading zeros (hex characters or bits, see WithDifficultyMode)
+// required until the first retarget.
+func NewBlockchain(difficulty int, opts ...Option) (*Blockchain, error) {
 	bc := &Blockchain{
-		chain:      make([]Block, 0),
-		difficulty: difficulty,
+		chain:         make([]Block, 0),
+		nodes:         make(map[Hash]*blockNode),
+		params:        Params{Difficulty: difficulty, Mode: DifficultyHex, BlockReward: DefaultBlockReward},
+		store:         NewMemoryStore(),
+		ledger:        newLedger(),
+		mempool:       newMempool(DefaultMempoolCapacity),
+		maxBlockTxs:   DefaultMaxBlockTransactions,
+		events:        NewBroadcaster(),
+		hashAlgorithm: DefaultHashAlgorithm,
+	}
+
+	for _, opt := range opts {
+		opt(bc)
+	}
+
+	if _, err := ParseDifficultyMode(string(bc.params.Mode)); err != nil {
+		return nil, err
+	}
+	bc.params.Difficulty = bc.params.Mode.clamp(bc.params.Difficulty)
+
+	hasher, err := NewHasher(bc.hashAlgorithm)
+	if err != nil {
+		return nil, err
 	}
+	bc.params.Hasher = hasher
 
+	if bc.miner != "" && !IsAddress(bc.miner) {
+		return nil, fmt.Errorf("%w: miner %q", ErrInvalidAddress, bc.miner)
+	}
+
+	blocks, err := bc.store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading blocks: %w", err)
+	}
+
+	if len(blocks) == 0 {
+		genesis := newGenesisBlock(bc.params.Difficulty, bc.params.Hasher)
+		if err := bc.store.Append(genesis); err != nil {
+			return nil, fmt.Errorf("persisting genesis block: %w", err)
+		}
+		bc.chain = append(bc.chain, genesis)
+		bc.tip = bc.addNode(genesis, nil)
+		return bc, nil
+	}
+
+	// The genesis block is appended first and records the hasher of the chain
+	if bc.params.Hasher, err = NewHasher(blocks[0].HashAlgorithm); err != nil {
+		return nil, fmt.Errorf("%w: genesis block: %w", ErrInvalidStoredChain, err)
+	}
+
+	// The store holds every branch; only the heaviest is replayed and validated
+	tip, err := bc.loadTree(blocks)
+	if err != nil {
+		return nil, err
+	}
+	bc.chain = bc.branch(tip)
+	bc.tip = tip
+
+	report, ledger := validateChain(bc.chain, bc.params)
+	if !report.Valid {
+		first := report.Errors[0]
+		return nil, fmt.Errorf("%w: block %d: %s", ErrInvalidStoredChain, first.Index, first.Reason)
+	}
+	bc.ledger = ledger
+
+	return bc, nil
+}
+
+// newGenesisBlock records the base difficulty and the hash algorithm but is not mined
+func newGenesisBlock(difficulty int, hasher Hasher) Block {
 	genesis := Block{
 		Index:        0,
-		Timestamp:    time.Now(),
+		Timestamp:    GenesisTime,
 		Data:         "Genesis Block",
-		PreviousHash: "0",
+		PreviousHash: ZeroHash,
 		Nonce:        0,
+		Difficulty:   difficulty,
+		MerkleRoot:   SomeHash(ZeroHash),
+		Version:      CurrentEncoding,
+		// Not part of the header: a genesis claiming another algorithm fails its own hash check
+		HashAlgorithm: hasher.Algorithm(),
 	}
-	genesis.Hash = calculateHash(genesis)
-	bc.chain = append(bc.chain, genesis)
+	genesis.Hash = sumHeader(genesis, headerRefs{}, hasher)
 
-	return bc
+	return genesis
 }
 
 func (bc *Blockchain) Chain() []Block {
@@ -54,95 +221,319 @@ func (bc *Blockchain) Chain() []Block {
 	return chainCopy
 }
 
-func (bc *Blockchain) AddBlock(data string) Block {
-	bc.mu.Lock()
+// AddBlock mines and appends a block. Mining stops with a *MiningAbortedError
+// when ctx is cancelled; a context that ends while waiting for the chain lock
+// is detected as soon as the lock is acquired. The stats include the time
+// spent waiting for that lock.
+func (bc *Blockchain) AddBlock(ctx context.Context, payload Payload) (Block, MiningStats, error) {
+	if err := payload.verify(); err != nil {
+		return Block{}, MiningStats{}, err
+	}
+
+	lockWait := bc.lockTimed()
 	defer bc.mu.Unlock()
 
-	previousBlock := bc.chain[len(bc.chain)-1]
+	newBlock, err := bc.nextBlock(payload)
+	if err != nil {
+		return Block{}, MiningStats{LockWaitMs: milliseconds(lockWait)}, err
+	}
+
+	stats, err := bc.mineBlock(ctx, &newBlock, payload.Mining)
+	stats.LockWaitMs = milliseconds(lockWait)
+	if err != nil {
+		return Block{}, stats, err
+	}
+	if err := bc.appendBlock(newBlock); err != nil {
+		return Block{}, stats, err
+	}
+
+	return newBlock, stats, nil
+}
+
+// nextBlock builds an unmined block on top of the current tip: the coinbase,
+// the payload transactions and, when asked, the best pending transactions.
+// Transfers are checked against the ledger. The payload must have been
+// verified. Callers must hold bc.mu (a read lock is enough).
+func (bc *Blockchain) nextBlock(payload Payload) (Block, error) {
+	return bc.nextBlockOn(bc.chain, bc.ledger, payload)
+}
+
+// nextBlockOn builds an unmined block on top of branch, whose state is ledger
+func (bc *Blockchain) nextBlockOn(branch []Block, ledger *Ledger, payload Payload) (Block, error) {
+	previousBlock := branch[len(branch)-1]
 
-	newBlock := Block{
+	// Round(0) drops the monotonic clock reading so the in-memory timestamp
+	// is identical to the one recovered from a JSON round trip
+	block := Block{
 		Index:        previousBlock.Index + 1,
-		Timestamp:    time.Now(),
-		Data:         data,
+		Timestamp:    time.Now().Round(0),
+		Data:         payload.Data,
 		PreviousHash: previousBlock.Hash,
 		Nonce:        0,
+		Difficulty:   bc.params.nextDifficulty(branch),
+		Transactions: payload.Transactions,
+		Version:      CurrentEncoding,
 	}
 
-	bc.mineBlock(&newBlock)
-	bc.chain = append(bc.chain, newBlock)
+	if payload.FromMempool {
+		pending := bc.mempool.selectFor(ledger, bc.maxBlockTxs)
+		if len(pending) == 0 && payload.Data == "" && len(payload.Transactions) == 0 {
+			return Block{}, ErrNothingToMine
+		}
+		block.Transactions = append(slices.Clip(payload.Transactions), pending...)
+	}
 
-	return newBlock
+	miner := payload.Miner
+	if miner == "" {
+		miner = bc.miner
+	}
+	fees, err := blockFees(block.Transactions)
+	if err != nil {
+		return Block{}, err
+	}
+	if miner != "" && bc.params.BlockReward+fees > 0 {
+		coinbase := Transaction{
+			Payload:   "coinbase",
+			Timestamp: block.Timestamp,
+			To:        miner,
+			Amount:    bc.params.BlockReward + fees,
+			Nonce:     uint64(block.Index),
+		}
+		coinbase.ID = coinbase.ComputeID()
+		block.Transactions = append([]Transaction{coinbase}, block.Transactions...)
+	}
+
+	if err := ledger.check(block, bc.params.BlockReward); err != nil {
+		return Block{}, err
+	}
+
+	merkleRoot, err := MerkleRoot(block.Transactions)
+	if err != nil {
+		return Block{}, err
+	}
+	block.MerkleRoot = SomeHash(merkleRoot)
+
+	return block, nil
+}
+
+// appendBlock persists a block mined on the tip, extends the chain and
+// applies its transfers to the ledger. Callers must hold bc.mu.
+func (bc *Blockchain) appendBlock(block Block) error {
+	touched, err := bc.ledger.replay(block, bc.params.BlockReward)
+	if err != nil {
+		return err
+	}
+	if err := bc.store.Append(block); err != nil {
+		return fmt.Errorf("persisting block %d: %w", block.Index, err)
+	}
+	bc.chain = append(bc.chain, block)
+	bc.tip = bc.addNode(block, bc.tip)
+	bc.ledger.commit(touched)
+	bc.mempool.removeMined(block, bc.ledger)
+	bc.notify(block)
+	return nil
+}
+
+// OnBlock registers fn to be called with every block added to the tree,
+// canonical or not, whether mined here or submitted. fn runs with the chain
+// lock held: it must return quickly and must not call back into bc.
+func (bc *Blockchain) OnBlock(fn func(Block)) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.listeners = append(bc.listeners, fn)
+}
+
+// notify runs the block listeners and publishes the block to event
+// subscribers. Callers must hold bc.mu.
+func (bc *Blockchain) notify(block Block) {
+	for _, fn := range bc.listeners {
+		fn(block)
+	}
+	if bc.events.Subscribers() > 0 {
+		bc.events.Publish(Event{Type: EventBlock, Block: &block})
+	}
 }
 
-// MineParallel demonstrates work-stealing and goroutine distribution across Ps
-func (bc *Blockchain) MineParallel(data string, numGoroutines int) ([]Block, time.Duration) {
+// MineParallel demonstrates work-stealing and goroutine distribution across Ps.
+// Each goroutine mines one block from payload, with its worker ID appended to
+// the data. Goroutines that find the mempool drained stop without error.
+// The stats of each mined block name the goroutine that mined it.
+func (bc *Blockchain) MineParallel(ctx context.Context, payload Payload, numGoroutines int) ([]Block, []MiningStats, time.Duration, error) {
+	type mined struct {
+		block Block
+		stats MiningStats
+	}
+
 	start := time.Now()
 	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var mineErr error
 	blocks := make([]Block, 0, numGoroutines)
-	blocksChan := make(chan Block, numGoroutines)
+	stats := make([]MiningStats, 0, numGoroutines)
+	minedChan := make(chan mined, numGoroutines)
 
 	for i := 0; i < numGoroutines; i++ {
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
 
-			blockData := data + "-worker-" + strconv.Itoa(id)
-			block := bc.AddBlock(blockData)
-			blocksChan <- block
+			block, blockStats, err := bc.AddBlock(ctx, payload.forWorker(id))
+			if errors.Is(err, ErrNothingToMine) {
+				return
+			}
+			if err != nil {
+				errOnce.Do(func() { mineErr = err })
+				return
+			}
+			blockStats.Worker = id
+			minedChan <- mined{block: block, stats: blockStats}
 		}(i)
 	}
 
 	go func() {
 		wg.Wait()
-		close(blocksChan)
+		close(minedChan)
 	}()
 
-	for block := range blocksChan {
-		blocks = append(blocks, block)
+	for m := range minedChan {
+		blocks = append(blocks, m.block)
+		stats = append(stats, m.stats)
+	}
+
+	if len(blocks) == 0 && mineErr == nil {
+		mineErr = ErrNothingToMine
 	}
 
 	duration := time.Since(start)
-	return blocks, duration
+	return blocks, stats, duration, mineErr
 }
 
-func (bc *Blockchain) mineBlock(block *Block) {
-	target := ""
-	for i := 0; i < bc.difficulty; i++ {
-		target += "0"
+// forWorker tags the data of a block mined by one of several goroutines
+func (p Payload) forWorker(id int) Payload {
+	if p.Data != "" {
+		p.Data += "-worker-" + strconv.Itoa(id)
 	}
+	return p
+}
 
-	for {
-		block.Hash = calculateHash(*block)
+// Header returns a copy of the block without its transactions. The header
+// alone is enough to recompute the block hash.
+func (b Block) Header() Block {
+	b.Transactions = nil
+	return b
+}
 
-		if block.Hash[:bc.difficulty] == target {
-			break
-		}
+// BlockAt returns the block at index
+func (bc *Blockchain) BlockAt(index int) (Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if index < 0 || index >= len(bc.chain) {
+		return Block{}, fmt.Errorf("%w: index %d", ErrBlockNotFound, index)
+	}
+	return bc.chain[index], nil
+}
 
-		block.Nonce++
+// Blocks iterates over up to limit canonical blocks from index from, or to
+// the tip when limit is 0, without copying the chain, and returns the length
+// of the chain iterated over. The chain is read as
+// it was when Blocks was called: blocks are never modified once appended,
+// appends write past the end of the snapshot and reorgs install a new
+// slice, so iterating needs no lock.
+func (bc *Blockchain) Blocks(from, limit int) (iter.Seq[Block], int) {
+	bc.mu.RLock()
+	chain := bc.chain
+	bc.mu.RUnlock()
+
+	from = min(max(from, 0), len(chain))
+	to := len(chain)
+	if limit > 0 {
+		to = min(from+limit, to)
+	}
 
-		// Yield to scheduler every 100k iterations to allow other goroutines to execute
-		if block.Nonce%100000 == 0 {
-			runtime.Gosched()
+	return func(yield func(Block) bool) {
+		for i := from; i < to; i++ {
+			if !yield(chain[i]) {
+				return
+			}
 		}
+	}, len(chain)
+}
+
+// BlockRange returns up to limit canonical blocks starting at index from,
+// and the length of the chain they were taken from. Only the range is copied.
+func (bc *Blockchain) BlockRange(from, limit int) ([]Block, int) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	if from < 0 || from >= len(bc.chain) || limit <= 0 {
+		return []Block{}, len(bc.chain)
+	}
+	to := min(from+limit, len(bc.chain))
+	return slices.Clone(bc.chain[from:to]), len(bc.chain)
+}
+
+// HasBlock reports whether the block is known, on any branch
+func (bc *Blockchain) HasBlock(hash Hash) bool {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	_, ok := bc.nodes[hash]
+	return ok
+}
+
+// BlockByHash returns a known block, on any branch. The block tree doubles
+// as the hash index.
+func (bc *Blockchain) BlockByHash(hash Hash) (Block, error) {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	node, ok := bc.nodes[hash]
+	if !ok {
+		return Block{}, fmt.Errorf("%w: hash %s", ErrBlockNotFound, hash)
 	}
+	return node.block, nil
+}
+
+// Tip returns the last block of the canonical chain
+func (bc *Blockchain) Tip() Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.tip.block
+}
+
+// Genesis returns the first block of the chain
+func (bc *Blockchain) Genesis() Block {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.chain[0]
 }
 
-func calculateHash(block Block) string {
-	record := strconv.Itoa(block.Index) +
-		block.Timestamp.String() +
-		block.Data +
-		block.PreviousHash +
-		strconv.Itoa(block.Nonce)
+// TransactionProof returns the header of the block at index and the Merkle
+// proof of inclusion of txID in it
+func (bc *Blockchain) TransactionProof(index int, txID string) (Block, MerkleProof, error) {
+	block, err := bc.BlockAt(index)
+	if err != nil {
+		return Block{}, MerkleProof{}, err
+	}
+
+	proof, err := BuildMerkleProof(block.Transactions, txID)
+	if err != nil {
+		return Block{}, MerkleProof{}, err
+	}
 
-	h := sha256.New()
-	h.Write([]byte(record))
-	hashed := h.Sum(nil)
+	return block.Header(), proof, nil
+}
 
-	return hex.EncodeToString(hashed)
+// Params returns the consensus rules of the chain
+func (bc *Blockchain) Params() Params {
+	return bc.params
 }
 
+// Difficulty returns the difficulty the next block will be mined at
 func (bc *Blockchain) Difficulty() int {
-	return bc.difficulty
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.params.nextDifficulty(bc.chain)
 }
 
 func (bc *Blockchain) Length() int {
@@ -150,3 +541,11 @@ func (bc *Blockchain) Length() int {
 	defer bc.mu.RUnlock()
 	return len(bc.chain)
 }
+
+// Close ends the event subscriptions and releases the underlying block store
+func (bc *Blockchain) Close() error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.events.Close()
+	return bc.store.Close()
+}
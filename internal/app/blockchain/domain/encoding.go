@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// EncodingLegacy hashes the concatenation of index, Timestamp.String(), data,
+	// previous hash and nonce. Timestamp.String() includes the monotonic clock
+	// reading, so legacy blocks can only be verified inside the process that
+	// mined them.
+	EncodingLegacy uint8 = 0
+
+	// EncodingV1 hashes a fixed binary header:
+	//
+	//	version      uint8
+	//	index        uint64 big-endian
+	//	nonce        uint64 big-endian
+	//	timestamp    int64 big-endian (UnixNano)
+	//	data length  uint32 big-endian
+	//	data         raw bytes
+	//	previous     32 raw bytes (hex-decoded previous hash)
+	EncodingV1 uint8 = 1
+
+	// EncodingV2 extends V1 with the difficulty the block was mined at,
+	// written as a uint32 big-endian right after the timestamp
+	EncodingV2 uint8 = 2
+
+	// EncodingV3 extends V2 with the Merkle root of the block transactions,
+	// written as 32 raw bytes after the previous hash
+	EncodingV3 uint8 = 3
+
+	// CurrentEncoding is used for every newly created block
+	CurrentEncoding = EncodingV3
+
+	// HashSize is the length in bytes of a block hash
+	HashSize = sha256.Size
+
+	// headerFixedSize is the size of a V3 header without the data bytes
+	headerFixedSize = 1 + 8 + 8 + 8 + 4 + 4 + 2*HashSize
+)
+
+type (
+	// Hash is a raw digest. It is a plain array, so blocks hold no pointer
+	// for their hashes and the GC has nothing to scan or free for them. It is
+	// written to JSON and text as 64 lowercase hex characters.
+	Hash [HashSize]byte
+
+	// OptionalHash is a hash that may be absent, like the Merkle root of a
+	// block encoded before EncodingV3. An absent hash is omitted from JSON by
+	// omitzero, while a present ZeroHash is written.
+	OptionalHash struct {
+		Hash  Hash
+		Valid bool
+	}
+
+	// headerRefs are the hashes referenced by a header, read once per block
+	// instead of once per nonce
+	headerRefs struct {
+		previous   Hash
+		merkleRoot Hash
+	}
+)
+
+var (
+	// ZeroHash is the previous hash of the genesis block
+	ZeroHash Hash
+
+	ErrInvalidHash         = errors.New("invalid hash")
+	ErrUnsupportedEncoding = errors.New("unsupported block encoding version")
+)
+
+// EncodeHeader returns the canonical bytes hashed for the block according to
+// its encoding version. Legacy blocks have no canonical binary form.
+func EncodeHeader(block Block) ([]byte, error) {
+	if block.Version < EncodingV1 || block.Version > EncodingV3 {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedEncoding, block.Version)
+	}
+
+	refs, err := decodeHeaderRefs(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendHeader(make([]byte, 0, headerFixedSize+len(block.Data)), block, refs), nil
+}
+
+func decodeHeaderRefs(block Block) (headerRefs, error) {
+	refs := headerRefs{previous: block.PreviousHash}
+	if block.Version >= EncodingV3 {
+		if !block.MerkleRoot.Valid {
+			return refs, fmt.Errorf("merkle root: %w: missing", ErrInvalidHash)
+		}
+		refs.merkleRoot = block.MerkleRoot.Hash
+	}
+
+	return refs, nil
+}
+
+// appendHeader writes the V1, V2 or V3 header of block, depending on block.Version
+func appendHeader(dst []byte, block Block, refs headerRefs) []byte {
+	dst = append(dst, block.Version)
+	dst = binary.BigEndian.AppendUint64(dst, uint64(block.Index))
+	dst = binary.BigEndian.AppendUint64(dst, uint64(block.Nonce))
+	dst = binary.BigEndian.AppendUint64(dst, uint64(block.Timestamp.UnixNano()))
+	if block.Version >= EncodingV2 {
+		dst = binary.BigEndian.AppendUint32(dst, uint32(block.Difficulty))
+	}
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(block.Data)))
+	dst = append(dst, block.Data...)
+	dst = append(dst, refs.previous[:]...)
+	if block.Version >= EncodingV3 {
+		dst = append(dst, refs.merkleRoot[:]...)
+	}
+	return dst
+}
+
+func ParseHash(s string) (Hash, error) {
+	digest, err := decodeHash(s)
+	return Hash(digest), err
+}
+
+func (h Hash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func (h Hash) IsZero() bool {
+	return h == ZeroHash
+}
+
+func (h Hash) MarshalText() ([]byte, error) {
+	return hex.AppendEncode(make([]byte, 0, 2*HashSize), h[:]), nil
+}
+
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := ParseHash(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// SomeHash returns a present OptionalHash holding h
+func SomeHash(h Hash) OptionalHash {
+	return OptionalHash{Hash: h, Valid: true}
+}
+
+// String returns the hex encoding of the hash, or an empty string when it is absent
+func (o OptionalHash) String() string {
+	if !o.Valid {
+		return ""
+	}
+	return o.Hash.String()
+}
+
+// IsZero reports whether the hash is absent, for omitzero
+func (o OptionalHash) IsZero() bool {
+	return !o.Valid
+}
+
+func (o OptionalHash) MarshalText() ([]byte, error) {
+	if !o.Valid {
+		return []byte{}, nil
+	}
+	return o.Hash.MarshalText()
+}
+
+// UnmarshalText reads an empty string as an absent hash
+func (o *OptionalHash) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*o = OptionalHash{}
+		return nil
+	}
+	if err := o.Hash.UnmarshalText(text); err != nil {
+		return err
+	}
+	o.Valid = true
+	return nil
+}
+
+func decodeHash(s string) ([HashSize]byte, error) {
+	var out [HashSize]byte
+
+	if hex.DecodedLen(len(s)) != HashSize {
+		return out, fmt.Errorf("%w: %q is not %d hex characters", ErrInvalidHash, s, HashSize*2)
+	}
+	if _, err := hex.Decode(out[:], []byte(s)); err != nil {
+		return out, fmt.Errorf("%w: %v", ErrInvalidHash, err)
+	}
+
+	return out, nil
+}
+
+// calculateHash dispatches on the block encoding version so chains mined
+// before the canonical encoding still validate in the process that built
+// them. Legacy blocks predate hasher and are always SHA-256.
+func calculateHash(block Block, hasher Hasher) (Hash, error) {
+	switch block.Version {
+	case EncodingLegacy:
+		return calculateLegacyHash(block), nil
+	case EncodingV1, EncodingV2, EncodingV3:
+		refs, err := decodeHeaderRefs(block)
+		if err != nil {
+			return Hash{}, err
+		}
+		return sumHeader(block, refs, hasher), nil
+	default:
+		return Hash{}, fmt.Errorf("%w: %d", ErrUnsupportedEncoding, block.Version)
+	}
+}
+
+func sumHeader(block Block, refs headerRefs, hasher Hasher) Hash {
+	return Hash(hasher.Sum(appendHeader(nil, block, refs)))
+}
+
+func calculateLegacyHash(block Block) Hash {
+	record := strconv.Itoa(block.Index) +
+		block.Timestamp.String() +
+		block.Data +
+		block.PreviousHash.String() +
+		strconv.Itoa(block.Nonce)
+
+	return sha256.Sum256([]byte(record))
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// The expected hashes and JSON were produced by the tree that still kept
+// hashes as hex strings, so they pin the preimages and the API output.
+func TestHashEncodingsAreStable(t *testing.T) {
+	timestamp := time.Date(2025, time.March, 1, 12, 30, 0, 123456789, time.UTC)
+	previous := mustParseHash(t, "00000abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234")
+
+	tests := []struct {
+		name     string
+		block    Block
+		wantHash string
+		wantJSON string
+	}{
+		{
+			name:     "legacy",
+			block:    Block{Index: 7, Timestamp: timestamp, Data: "legacy", PreviousHash: previous, Nonce: 42},
+			wantHash: "bba6fa24a4ff036cf562ca5860daa3ea9a2dab7e2b47b47ff6295b827e352638",
+		},
+		{
+			name:     "v1 without merkle root",
+			block:    Block{Index: 7, Timestamp: timestamp, Data: "v1", PreviousHash: previous, Nonce: 42, Version: EncodingV1},
+			wantHash: "51eb7d394c2f6a070c8d400fd644614b0439e7fae0f60191bb5de1ca445ab2f8",
+			wantJSON: `{"index":7,"timestamp":"2025-03-01T12:30:00.123456789Z","data":"v1","previous_hash":"00000abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234","hash":"51eb7d394c2f6a070c8d400fd644614b0439e7fae0f60191bb5de1ca445ab2f8","nonce":42,"difficulty":0,"version":1}`,
+		},
+		{
+			name:     "v3 with the zero merkle root",
+			block:    Block{Index: 7, Timestamp: timestamp, Data: "v3", PreviousHash: previous, Nonce: 42, Difficulty: 3, Version: EncodingV3, MerkleRoot: SomeHash(ZeroHash)},
+			wantHash: "cccb9503f3b669acd20554154d84bb45dcaf845404862ac725aba5c63362be64",
+			wantJSON: `{"index":7,"timestamp":"2025-03-01T12:30:00.123456789Z","data":"v3","previous_hash":"00000abcdef0123456789abcdef0123456789abcdef0123456789abcdef01234","hash":"cccb9503f3b669acd20554154d84bb45dcaf845404862ac725aba5c63362be64","nonce":42,"difficulty":3,"merkle_root":"0000000000000000000000000000000000000000000000000000000000000000","version":3}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := calculateHash(tt.block, sha256Hasher{})
+			if err != nil {
+				t.Fatalf("calculateHash: %v", err)
+			}
+			if hash.String() != tt.wantHash {
+				t.Fatalf("hash = %s, want %s", hash, tt.wantHash)
+			}
+			if tt.wantJSON == "" {
+				return
+			}
+
+			tt.block.Hash = hash
+			encoded, err := json.Marshal(tt.block)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			if string(encoded) != tt.wantJSON {
+				t.Fatalf("json =\n%s\nwant\n%s", encoded, tt.wantJSON)
+			}
+
+			var decoded Block
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if decoded.MerkleRoot != tt.block.MerkleRoot || decoded.Hash != tt.block.Hash {
+				t.Fatalf("round trip changed the hashes: %+v", decoded)
+			}
+		})
+	}
+}
+
+func mustParseHash(t *testing.T, s string) Hash {
+	t.Helper()
+	hash, err := ParseHash(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
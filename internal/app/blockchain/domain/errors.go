@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MiningAbortedError is returned when mining stops before a valid nonce is
+// found because the context was cancelled or its deadline expired. Err is
+// the cause of the cancellation.
+type MiningAbortedError struct {
+	Index       int
+	NoncesTried int
+	Err         error
+}
+
+func (e *MiningAbortedError) Error() string {
+	return fmt.Sprintf("mining block %d aborted after %d nonces: %v", e.Index, e.NoncesTried, e.Err)
+}
+
+func (e *MiningAbortedError) Unwrap() error {
+	return e.Err
+}
+
+// IsMiningTimeout reports whether err is a mining abort caused by a deadline
+func IsMiningTimeout(err error) bool {
+	var aborted *MiningAbortedError
+	return errors.As(err, &aborted) && errors.Is(aborted.Err, context.DeadlineExceeded)
+}
+
+// IsMiningCanceled reports whether err is a mining abort caused by cancellation
+func IsMiningCanceled(err error) bool {
+	var aborted *MiningAbortedError
+	return errors.As(err, &aborted) && errors.Is(aborted.Err, context.Canceled)
+}
@@ -0,0 +1,204 @@
+package domain
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// EventBlock is published for every block added to the tree
+	EventBlock EventType = "block"
+	// EventProgress is published periodically while a block is being mined
+	EventProgress EventType = "progress"
+
+	// DefaultSubscriberBuffer is the number of events a subscriber may fall
+	// behind before it is dropped
+	DefaultSubscriberBuffer = 64
+
+	// progressInterval is the minimum time between two progress events of
+	// the same mining goroutine
+	progressInterval = 250 * time.Millisecond
+)
+
+type (
+	EventType string
+
+	Event struct {
+		// ID increases by one with every published event
+		ID       uint64          `json:"id"`
+		Type     EventType       `json:"type"`
+		Block    *Block          `json:"block,omitempty"`
+		Progress *MiningProgress `json:"progress,omitempty"`
+	}
+
+	// MiningProgress reports the nonces tried so far for a block
+	MiningProgress struct {
+		Index      int `json:"index"`
+		Difficulty int `json:"difficulty"`
+		// Worker is the goroutine searching, for split-nonce mining; 0 otherwise
+		Worker      int     `json:"worker"`
+		Nonce       int     `json:"nonce"`
+		NoncesTried int     `json:"nonces_tried"`
+		HashRate    float64 `json:"hash_rate"`
+	}
+
+	// Broadcaster fans events out to subscribers. Publishing never blocks:
+	// each subscriber has a bounded buffer, and a subscriber whose buffer is
+	// full is dropped rather than slowing down mining or the chain lock.
+	Broadcaster struct {
+		subscribers map[*Subscription]struct{}
+		count       atomic.Int64
+		nextID      uint64
+		dropped     int
+		closed      bool
+		mu          sync.Mutex
+	}
+
+	Subscription struct {
+		events      chan Event
+		types       map[EventType]bool
+		broadcaster *Broadcaster
+		dropped     bool
+	}
+)
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subscribers: make(map[*Subscription]struct{})}
+}
+
+// Subscribe returns a subscription receiving the events of the given types,
+// or of every type when none is given. The subscriber must drain Events
+// and Close the subscription when done.
+func (b *Broadcaster) Subscribe(buffer int, types ...EventType) *Subscription {
+	sub := &Subscription{
+		events:      make(chan Event, max(buffer, 1)),
+		broadcaster: b,
+	}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	b.count.Store(int64(len(b.subscribers)))
+	return sub
+}
+
+// Publish delivers event to every interested subscriber, dropping the ones
+// whose buffer is full
+func (b *Broadcaster) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = b.nextID
+	for sub := range b.subscribers {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped = true
+			b.dropped++
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribers returns the number of subscribers without locking, so
+// publishers can skip building events nobody listens to
+func (b *Broadcaster) Subscribers() int {
+	return int(b.count.Load())
+}
+
+// Dropped returns the number of subscribers dropped for falling behind
+func (b *Broadcaster) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
+
+// Close ends every subscription. Later subscriptions are closed immediately.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// remove closes the channel of sub. Callers must hold b.mu.
+func (b *Broadcaster) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+	b.count.Store(int64(len(b.subscribers)))
+}
+
+// Events is closed when the subscription ends: on Close, when the
+// broadcaster closes, or when the subscriber fell behind (see Dropped)
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped reports whether the subscription ended because its buffer was
+// full. It is meaningful once Events is closed.
+func (s *Subscription) Dropped() bool {
+	s.broadcaster.mu.Lock()
+	defer s.broadcaster.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) Close() {
+	s.broadcaster.mu.Lock()
+	defer s.broadcaster.mu.Unlock()
+	s.broadcaster.remove(s)
+}
+
+// progressReporter returns the callback nonceSearch.run calls every
+// cancelCheckInterval hashes. It publishes a progress event at most every
+// progressInterval, and only reads the clock while someone is subscribed.
+func (bc *Blockchain) progressReporter(block *Block, worker int) func(hashes int) {
+	start := time.Now()
+	last := start
+
+	return func(hashes int) {
+		if bc.events.Subscribers() == 0 {
+			return
+		}
+		now := time.Now()
+		if now.Sub(last) < progressInterval {
+			return
+		}
+		last = now
+
+		bc.events.Publish(Event{Type: EventProgress, Progress: &MiningProgress{
+			Index:       block.Index,
+			Difficulty:  block.Difficulty,
+			Worker:      worker,
+			Nonce:       block.Nonce,
+			NoncesTried: hashes,
+			HashRate:    HashRate(hashes, milliseconds(now.Sub(start))),
+		}})
+	}
+}
+
+// Events returns the broadcaster of the chain: blocks as they are added and
+// mining progress
+func (bc *Blockchain) Events() *Broadcaster {
+	return bc.events
+}
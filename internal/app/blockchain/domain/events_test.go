@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBroadcasterDropsSlowSubscribers(t *testing.T) {
+	tests := []struct {
+		name      string
+		buffer    int
+		types     []EventType
+		published []EventType
+		// wantIDs are the events left in the buffer, in order
+		wantIDs     []uint64
+		wantDropped bool
+	}{
+		{
+			name:      "room in the buffer",
+			buffer:    3,
+			published: []EventType{EventBlock, EventProgress, EventBlock},
+			wantIDs:   []uint64{1, 2, 3},
+		},
+		{
+			name:        "full buffer drops the subscriber",
+			buffer:      2,
+			published:   []EventType{EventBlock, EventBlock, EventBlock, EventBlock},
+			wantIDs:     []uint64{1, 2},
+			wantDropped: true,
+		},
+		{
+			name:        "buffer of at least one",
+			buffer:      0,
+			published:   []EventType{EventProgress, EventProgress},
+			wantIDs:     []uint64{1},
+			wantDropped: true,
+		},
+		{
+			name:      "other types do not fill the buffer",
+			buffer:    1,
+			types:     []EventType{EventBlock},
+			published: []EventType{EventProgress, EventProgress, EventBlock, EventProgress},
+			wantIDs:   []uint64{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroadcaster()
+			sub := b.Subscribe(tt.buffer, tt.types...)
+			for _, eventType := range tt.published {
+				b.Publish(Event{Type: eventType})
+			}
+
+			wantSubscribers, wantDropped := 1, 0
+			if tt.wantDropped {
+				wantSubscribers, wantDropped = 0, 1
+				// A dropped subscriber still reads what was buffered, then the end
+				var ids []uint64
+				for event := range sub.Events() {
+					ids = append(ids, event.ID)
+				}
+				if !slices.Equal(ids, tt.wantIDs) {
+					t.Fatalf("received %v, want %v", ids, tt.wantIDs)
+				}
+			} else {
+				var ids []uint64
+				for range len(tt.wantIDs) {
+					ids = append(ids, (<-sub.Events()).ID)
+				}
+				if !slices.Equal(ids, tt.wantIDs) || len(sub.Events()) != 0 {
+					t.Fatalf("received %v and %d more, want %v", ids, len(sub.Events()), tt.wantIDs)
+				}
+			}
+
+			if sub.Dropped() != tt.wantDropped || b.Dropped() != wantDropped || b.Subscribers() != wantSubscribers {
+				t.Fatalf("dropped %t, broadcaster dropped %d with %d subscribers; want %t, %d and %d",
+					sub.Dropped(), b.Dropped(), b.Subscribers(), tt.wantDropped, wantDropped, wantSubscribers)
+			}
+		})
+	}
+}
+
+func TestBroadcasterEndsSubscriptions(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(b *Broadcaster, sub *Subscription)
+		// wantSubscribers is the number left of the two subscribers
+		wantSubscribers int
+	}{
+		{
+			name:            "unsubscribe",
+			end:             func(_ *Broadcaster, sub *Subscription) { sub.Close() },
+			wantSubscribers: 1,
+		},
+		{
+			name: "unsubscribe twice",
+			end: func(_ *Broadcaster, sub *Subscription) {
+				sub.Close()
+				sub.Close()
+			},
+			wantSubscribers: 1,
+		},
+		{
+			name: "broadcaster closed",
+			end:  func(b *Broadcaster, _ *Subscription) { b.Close() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroadcaster()
+			sub := b.Subscribe(DefaultSubscriberBuffer)
+			other := b.Subscribe(DefaultSubscriberBuffer)
+
+			tt.end(b, sub)
+			if _, open := <-sub.Events(); open {
+				t.Fatal("ended subscription still open")
+			}
+			if sub.Dropped() {
+				t.Fatal("ended subscription reported as dropped")
+			}
+			if got := b.Subscribers(); got != tt.wantSubscribers {
+				t.Fatalf("subscribers = %d, want %d", got, tt.wantSubscribers)
+			}
+
+			// Publishing after the end neither reaches nor drops anyone
+			b.Publish(Event{Type: EventBlock})
+			if tt.wantSubscribers == 1 && len(other.Events()) != 1 {
+				t.Fatalf("remaining subscriber has %d events, want 1", len(other.Events()))
+			}
+			if b.Dropped() != 0 {
+				t.Fatalf("broadcaster dropped %d subscribers", b.Dropped())
+			}
+		})
+	}
+
+	t.Run("subscribe after close", func(t *testing.T) {
+		b := NewBroadcaster()
+		b.Close()
+		if _, open := <-b.Subscribe(1).Events(); open || b.Subscribers() != 0 {
+			t.Fatalf("subscription to a closed broadcaster is open with %d subscribers", b.Subscribers())
+		}
+	})
+}
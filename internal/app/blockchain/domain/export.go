@@ -0,0 +1,293 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// ChainExportFormat identifies the files written by Export
+	ChainExportFormat = "go-runtime-demo/chain"
+	// ChainExportVersion is the layout version of the files written by Export
+	ChainExportVersion = 2
+
+	// ImportReplace swaps the whole chain, side branches included, for the
+	// imported one
+	ImportReplace ImportMode = "replace"
+	// ImportAppend appends the imported blocks past the tip, provided the
+	// imported chain extends the canonical one
+	ImportAppend ImportMode = "append"
+)
+
+var (
+	ErrInvalidImportMode = errors.New("invalid import mode")
+	// ErrIncompatibleExport is returned for files of another format or
+	// version, or whose chain was mined with other consensus settings
+	ErrIncompatibleExport = errors.New("incompatible chain export")
+	ErrInvalidImport      = errors.New("imported chain failed validation")
+	ErrImportNotExtending = errors.New("imported chain does not extend the current chain")
+)
+
+type (
+	ImportMode string
+
+	// ChainExport is a self-describing copy of the canonical chain. The
+	// settings the blocks were mined with travel with them, so an import can
+	// reject a file the node could not validate before looking at any block.
+	ChainExport struct {
+		Format         string         `json:"format"`
+		Version        int            `json:"version"`
+		ExportedAt     time.Time      `json:"exported_at"`
+		DifficultyMode DifficultyMode `json:"difficulty_mode"`
+		// Difficulty is the base difficulty, recorded by the genesis block
+		Difficulty int `json:"difficulty"`
+		// RetargetInterval is 0 and TargetBlockTime empty when the
+		// difficulty is fixed
+		RetargetInterval int           `json:"retarget_interval"`
+		TargetBlockTime  string        `json:"target_block_time,omitempty"`
+		BlockReward      uint64        `json:"block_reward"`
+		HashAlgorithm    HashAlgorithm `json:"hash_algorithm"`
+		Length           int           `json:"length"`
+		TipHash          Hash          `json:"tip_hash"`
+		Blocks           []Block       `json:"blocks"`
+	}
+
+	ImportResult struct {
+		Mode ImportMode `json:"mode"`
+		// Imported is the number of blocks added to the canonical chain
+		Imported int `json:"imported"`
+		// Replaced is the number of canonical blocks the import removed
+		Replaced int  `json:"replaced"`
+		Length   int  `json:"length"`
+		TipHash  Hash `json:"tip_hash"`
+		// DroppedTransactions counts the pending transactions removed because
+		// the imported blocks include them or made them invalid
+		DroppedTransactions int `json:"dropped_transactions"`
+	}
+
+	// ImportError reports every validation failure of an imported chain,
+	// block by block
+	ImportError struct {
+		Report ValidationReport
+	}
+)
+
+func (e *ImportError) Error() string {
+	first := e.Report.Errors[0]
+	return fmt.Sprintf("%s: block %d: %s: %s", ErrInvalidImport, first.Index, first.Code, first.Reason)
+}
+
+func (e *ImportError) Unwrap() error {
+	return ErrInvalidImport
+}
+
+func ParseImportMode(value string) (ImportMode, error) {
+	switch mode := ImportMode(value); mode {
+	case ImportReplace, ImportAppend:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidImportMode, value)
+	}
+}
+
+// Export copies the canonical chain along with the settings needed to
+// validate it
+func (bc *Blockchain) Export() ChainExport {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	blocks := make([]Block, len(bc.chain))
+	copy(blocks, bc.chain)
+
+	export := ChainExport{
+		Format:         ChainExportFormat,
+		Version:        ChainExportVersion,
+		ExportedAt:     time.Now().UTC(),
+		DifficultyMode: bc.params.Mode,
+		Difficulty:     bc.params.Difficulty,
+		BlockReward:    bc.params.BlockReward,
+		HashAlgorithm:  bc.params.Hasher.Algorithm(),
+		Length:         len(blocks),
+		TipHash:        bc.tip.block.Hash,
+		Blocks:         blocks,
+	}
+	if policy := bc.params.Retarget; policy.Enabled() {
+		export.RetargetInterval = policy.Interval
+		export.TargetBlockTime = policy.TargetBlockTime.String()
+	}
+	return export
+}
+
+// Import validates every block of an exported chain, then adds it under the
+// chain lock. Both modes persist the blocks in one store operation before
+// changing the chain, so a failure leaves both the store and the chain
+// untouched. ImportReplace swaps the chain with BlockStore.Replace;
+// ImportAppend appends the blocks past the tip with BlockStore.Append, then
+// notifies listeners and subscribers of each one like a mined block.
+func (bc *Blockchain) Import(export ChainExport, mode ImportMode) (ImportResult, error) {
+	if _, err := ParseImportMode(string(mode)); err != nil {
+		return ImportResult{}, err
+	}
+	if err := bc.checkExport(export); err != nil {
+		return ImportResult{}, err
+	}
+
+	// Validation needs no chain state, so it runs before taking the lock. The
+	// ledger it builds is the state after the imported tip in both modes.
+	blocks := export.Blocks
+	report, ledger := validateChain(blocks, bc.params)
+	if !report.Valid {
+		return ImportResult{}, &ImportError{Report: report}
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if mode == ImportAppend {
+		return bc.importAppend(blocks, ledger)
+	}
+	return bc.importReplace(blocks, ledger)
+}
+
+// checkExport rejects files written by another format version or mined with
+// settings other than the node's
+func (bc *Blockchain) checkExport(export ChainExport) error {
+	if export.Format != ChainExportFormat || export.Version != ChainExportVersion {
+		return fmt.Errorf("%w: format %q version %d (expected %q version %d)",
+			ErrIncompatibleExport, export.Format, export.Version, ChainExportFormat, ChainExportVersion)
+	}
+	if len(export.Blocks) == 0 {
+		return fmt.Errorf("%w: no blocks", ErrIncompatibleExport)
+	}
+	if export.Length != len(export.Blocks) {
+		return fmt.Errorf("%w: length %d but %d blocks", ErrIncompatibleExport, export.Length, len(export.Blocks))
+	}
+
+	if export.DifficultyMode != bc.params.Mode || export.Difficulty != bc.params.Difficulty {
+		return fmt.Errorf("%w: difficulty %d %s (node uses %d %s)",
+			ErrIncompatibleExport, export.Difficulty, export.DifficultyMode, bc.params.Difficulty, bc.params.Mode)
+	}
+	// The header alone does not bind the blocks: a chain mined at a lower
+	// difficulty from its genesis block on would otherwise validate
+	if difficulty := bc.params.effectiveDifficulty(export.Blocks[0]); difficulty != bc.params.Difficulty {
+		return fmt.Errorf("%w: genesis block has difficulty %d (node uses %d)", ErrIncompatibleExport, difficulty, bc.params.Difficulty)
+	}
+
+	policy, err := export.retargetPolicy()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrIncompatibleExport, err)
+	}
+	if node := bc.params.Retarget; describeRetarget(policy) != describeRetarget(node) {
+		return fmt.Errorf("%w: %s (node uses %s)", ErrIncompatibleExport, describeRetarget(policy), describeRetarget(node))
+	}
+	if export.BlockReward != bc.params.BlockReward {
+		return fmt.Errorf("%w: block reward %d (node uses %d)", ErrIncompatibleExport, export.BlockReward, bc.params.BlockReward)
+	}
+
+	genesis, err := NewHasher(export.Blocks[0].HashAlgorithm)
+	if err != nil {
+		return fmt.Errorf("%w: genesis block: %w", ErrIncompatibleExport, err)
+	}
+	if export.HashAlgorithm != genesis.Algorithm() {
+		return fmt.Errorf("%w: hash algorithm %s but the genesis block records %s",
+			ErrIncompatibleExport, export.HashAlgorithm, genesis.Algorithm())
+	}
+	if algorithm := bc.params.Hasher.Algorithm(); export.HashAlgorithm != algorithm {
+		return fmt.Errorf("%w: hash algorithm %s (node uses %s)", ErrIncompatibleExport, export.HashAlgorithm, algorithm)
+	}
+
+	return nil
+}
+
+// retargetPolicy reads back the retarget policy recorded by Export
+func (export ChainExport) retargetPolicy() (RetargetPolicy, error) {
+	policy := RetargetPolicy{Interval: export.RetargetInterval}
+	if export.TargetBlockTime == "" {
+		return policy, nil
+	}
+	target, err := time.ParseDuration(export.TargetBlockTime)
+	if err != nil {
+		return RetargetPolicy{}, fmt.Errorf("target block time: %w", err)
+	}
+	policy.TargetBlockTime = target
+	return policy, nil
+}
+
+// describeRetarget names policy, so that policies that never change the
+// difficulty compare equal
+func describeRetarget(policy RetargetPolicy) string {
+	if !policy.Enabled() {
+		return "fixed difficulty"
+	}
+	return fmt.Sprintf("retarget every %d blocks to %s", policy.Interval, policy.TargetBlockTime)
+}
+
+// importAppend adds the blocks past the tip when blocks contains the
+// canonical chain. Validation linked every hash to the previous one, so
+// finding the tip at its height is enough, and ledger, the state after the
+// last imported block, becomes the canonical ledger. Callers must hold bc.mu.
+func (bc *Blockchain) importAppend(blocks []Block, ledger *Ledger) (ImportResult, error) {
+	tip := bc.tip.block
+	if len(blocks) <= tip.Index || blocks[tip.Index].Hash != tip.Hash {
+		return ImportResult{}, fmt.Errorf("%w: tip %d (%s) is not part of the imported chain", ErrImportNotExtending, tip.Index, tip.Hash)
+	}
+
+	added := blocks[tip.Index+1:]
+	if err := bc.store.Append(added...); err != nil {
+		return ImportResult{}, fmt.Errorf("persisting imported blocks: %w", err)
+	}
+
+	pending := bc.mempool.Len()
+	bc.chain = append(bc.chain, added...)
+	bc.ledger = ledger
+	for _, block := range added {
+		bc.tip = bc.addNode(block, bc.tip)
+		bc.mempool.removeMined(block, ledger)
+		bc.notify(block)
+	}
+
+	return ImportResult{
+		Mode:                ImportAppend,
+		Imported:            len(added),
+		Length:              len(bc.chain),
+		TipHash:             bc.tip.block.Hash,
+		DroppedTransactions: pending - bc.mempool.Len(),
+	}, nil
+}
+
+// importReplace persists blocks in place of every stored block and makes
+// them the whole tree. Pending transactions are admitted again against the
+// new ledger. Callers must hold bc.mu.
+func (bc *Blockchain) importReplace(blocks []Block, ledger *Ledger) (ImportResult, error) {
+	if err := bc.store.Replace(blocks); err != nil {
+		return ImportResult{}, fmt.Errorf("persisting imported chain: %w", err)
+	}
+
+	common := 0
+	for common < len(bc.chain) && common < len(blocks) && bc.chain[common].Hash == blocks[common].Hash {
+		common++
+	}
+	replaced := len(bc.chain) - common
+
+	bc.nodes = make(map[Hash]*blockNode, len(blocks))
+	var tip *blockNode
+	for _, block := range blocks {
+		tip = bc.addNode(block, tip)
+	}
+	bc.chain = blocks
+	bc.tip = tip
+	bc.ledger = ledger
+	bc.side = sideLedger{}
+
+	dropped := bc.mempool.readmit(nil, blocks, ledger)
+
+	return ImportResult{
+		Mode:                ImportReplace,
+		Imported:            len(blocks) - common,
+		Replaced:            replaced,
+		Length:              len(bc.chain),
+		TipHash:             bc.tip.block.Hash,
+		DroppedTransactions: dropped,
+	}, nil
+}
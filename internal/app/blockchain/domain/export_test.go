@@ -0,0 +1,205 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestImportRejectsIncompatibleExports(t *testing.T) {
+	source := newTestChain(t)
+	for range 3 {
+		mineTransactions(t, source, 1)
+	}
+
+	tests := []struct {
+		name    string
+		change  func(export *ChainExport)
+		wantErr error
+	}{
+		{
+			name:    "other version",
+			change:  func(export *ChainExport) { export.Version = 1 },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "no blocks",
+			change:  func(export *ChainExport) { export.Blocks, export.Length = nil, 0 },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "length not matching the blocks",
+			change:  func(export *ChainExport) { export.Length++ },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "other difficulty",
+			change:  func(export *ChainExport) { export.Difficulty = 2 },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "other difficulty mode",
+			change:  func(export *ChainExport) { export.DifficultyMode = DifficultyBits },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name: "retarget policy",
+			change: func(export *ChainExport) {
+				export.RetargetInterval, export.TargetBlockTime = 10, "5s"
+			},
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "unreadable target block time",
+			change:  func(export *ChainExport) { export.TargetBlockTime = "soon" },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "other block reward",
+			change:  func(export *ChainExport) { export.BlockReward = 1 },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "other hash algorithm",
+			change:  func(export *ChainExport) { export.HashAlgorithm = HashSHA3_256 },
+			wantErr: ErrIncompatibleExport,
+		},
+		{
+			name:    "tampered block",
+			change:  func(export *ChainExport) { export.Blocks[2].Data = "rewritten" },
+			wantErr: ErrInvalidImport,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []ImportMode{ImportAppend, ImportReplace} {
+			t.Run(tt.name+" "+string(mode), func(t *testing.T) {
+				export := source.Export()
+				tt.change(&export)
+
+				bc := newTestChain(t)
+				tip := bc.Tip()
+				if _, err := bc.Import(export, mode); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Import error = %v, want %v", err, tt.wantErr)
+				}
+				if bc.Tip().Hash != tip.Hash || bc.Length() != 1 {
+					t.Fatalf("rejected import changed the chain to %d blocks", bc.Length())
+				}
+			})
+		}
+	}
+}
+
+func TestImportRejectsLowDifficultyChain(t *testing.T) {
+	// A chain mined at difficulty 1 whose file claims the node's difficulty
+	weak := newTestChain(t)
+	mineTransactions(t, weak, 1)
+	export := weak.Export()
+	export.Difficulty = 2
+
+	bc, err := NewBlockchain(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = bc.Close() })
+
+	if _, err := bc.Import(export, ImportReplace); !errors.Is(err, ErrIncompatibleExport) {
+		t.Fatalf("Import error = %v, want %v", err, ErrIncompatibleExport)
+	}
+
+	// Rewriting the genesis difficulty breaks its hash and the link to block 1
+	export.Blocks[0].Difficulty = 2
+	if _, err := bc.Import(export, ImportReplace); !errors.Is(err, ErrInvalidImport) {
+		t.Fatalf("Import error = %v, want %v", err, ErrInvalidImport)
+	}
+}
+
+func TestImportModes(t *testing.T) {
+	source := newTestChain(t, WithMiner(miner))
+	for range 3 {
+		mineTransactions(t, source, 1)
+	}
+	export := source.Export()
+
+	t.Run("append extends the chain", func(t *testing.T) {
+		bc := newTestChain(t)
+		result, err := bc.Import(export, ImportAppend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Imported != 3 || bc.Tip().Hash != source.Tip().Hash {
+			t.Fatalf("imported %d blocks to tip %s, want 3 to %s", result.Imported, bc.Tip().Hash, source.Tip().Hash)
+		}
+		if got := bc.Account(miner); got != source.Account(miner) {
+			t.Fatalf("miner account = %+v, want %+v", got, source.Account(miner))
+		}
+	})
+
+	t.Run("append refuses a chain without the tip", func(t *testing.T) {
+		bc := newTestChain(t)
+		mineTransactions(t, bc, 1)
+		if _, err := bc.Import(export, ImportAppend); !errors.Is(err, ErrImportNotExtending) {
+			t.Fatalf("Import error = %v, want %v", err, ErrImportNotExtending)
+		}
+	})
+
+	t.Run("replace swaps a diverging chain", func(t *testing.T) {
+		bc := newTestChain(t)
+		mineTransactions(t, bc, 1)
+		result, err := bc.Import(export, ImportReplace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Replaced != 1 || result.Imported != 3 || bc.Tip().Hash != source.Tip().Hash {
+			t.Fatalf("result = %+v, want 1 replaced and 3 imported", result)
+		}
+		if got := bc.Account(miner); got != source.Account(miner) {
+			t.Fatalf("miner account = %+v, want %+v", got, source.Account(miner))
+		}
+	})
+
+	t.Run("append is all or nothing", func(t *testing.T) {
+		store := &failingStore{MemoryStore: NewMemoryStore()}
+		bc := newTestChain(t, WithStore(store))
+		store.fail = true
+
+		if _, err := bc.Import(export, ImportAppend); !errors.Is(err, errStoreFailed) {
+			t.Fatalf("Import error = %v, want %v", err, errStoreFailed)
+		}
+		stored, err := store.Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bc.Length() != 1 || len(stored) != 1 || bc.Account(miner).Balance != 0 {
+			t.Fatalf("failed import left %d blocks in the chain and %d in the store", bc.Length(), len(stored))
+		}
+	})
+}
+
+func TestExportRecordsConsensusSettings(t *testing.T) {
+	policy := RetargetPolicy{Interval: 5, TargetBlockTime: 2 * time.Second}
+	bc := newTestChain(t, WithRetarget(policy), WithBlockReward(7))
+
+	export := bc.Export()
+	if export.RetargetInterval != 5 || export.TargetBlockTime != "2s" || export.BlockReward != 7 {
+		t.Fatalf("export records retarget %d %q and reward %d", export.RetargetInterval, export.TargetBlockTime, export.BlockReward)
+	}
+	if _, err := bc.Import(export, ImportAppend); err != nil {
+		t.Fatalf("importing its own export: %v", err)
+	}
+}
+
+var errStoreFailed = errors.New("store failed")
+
+// failingStore fails every Append once fail is set
+type failingStore struct {
+	*MemoryStore
+	fail bool
+}
+
+func (s *failingStore) Append(blocks ...Block) error {
+	if s.fail {
+		return errStoreFailed
+	}
+	return s.MemoryStore.Append(blocks...)
+}
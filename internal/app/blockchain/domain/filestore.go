@@ -0,0 +1,405 @@
+package domain
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// SyncAlways fsyncs the log after every appended block
+	SyncAlways SyncMode = "always"
+	// SyncInterval fsyncs the log periodically from a background goroutine
+	SyncInterval SyncMode = "interval"
+	// SyncNever leaves flushing to the operating system
+	SyncNever SyncMode = "never"
+
+	fileStoreName = "chain.log"
+	// fileStoreMagic identifies the log format and its version
+	fileStoreMagic = "GRDLOG01"
+	// recordHeaderSize holds the payload length and its CRC-32C checksum
+	recordHeaderSize = 8
+	maxRecordSize    = 64 << 20
+)
+
+var (
+	ErrInvalidSyncMode = errors.New("invalid sync mode")
+	ErrStoreClosed     = errors.New("block store is closed")
+
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+type (
+	SyncMode string
+
+	FileStoreOptions struct {
+		Dir          string
+		SyncMode     SyncMode
+		SyncInterval time.Duration
+	}
+
+	// FileStore is an append-only log of JSON encoded blocks. Each record is
+	// framed as [length uint32][crc32c uint32][payload]. A torn or corrupted
+	// tail left by a crash is truncated when the log is loaded.
+	FileStore struct {
+		file    *os.File
+		options FileStoreOptions
+		dirty   bool
+		closed  bool
+		stop    chan struct{}
+		done    chan struct{}
+		mu      sync.Mutex
+	}
+)
+
+func ParseSyncMode(value string) (SyncMode, error) {
+	switch mode := SyncMode(value); mode {
+	case SyncAlways, SyncInterval, SyncNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidSyncMode, value)
+	}
+}
+
+func OpenFileStore(options FileStoreOptions) (*FileStore, error) {
+	if _, err := ParseSyncMode(string(options.SyncMode)); err != nil {
+		return nil, err
+	}
+	if options.SyncInterval <= 0 {
+		options.SyncInterval = time.Second
+	}
+
+	if err := os.MkdirAll(options.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(options.Dir, fileStoreName), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		file:    file,
+		options: options,
+	}, nil
+}
+
+// Load replays the log from the beginning, truncating any incomplete or
+// corrupted tail, and positions the file for further appends
+func (s *FileStore) Load() ([]Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+
+	if err := s.ensureHeader(); err != nil {
+		return nil, err
+	}
+
+	blocks, validSize, err := s.readRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.truncateTail(validSize); err != nil {
+		return nil, err
+	}
+
+	if s.options.SyncMode == SyncInterval && s.stop == nil {
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.syncLoop()
+	}
+
+	return blocks, nil
+}
+
+// Append writes the records of blocks with a single write, which is cut
+// back if it or the fsync fails
+func (s *FileStore) Append(blocks ...Block) error {
+	var records []byte
+	for _, block := range blocks {
+		record, err := encodeRecord(block)
+		if err != nil {
+			return err
+		}
+		records = append(records, record...)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	offset, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write(records); err != nil {
+		return s.rollback(offset, err)
+	}
+
+	if s.options.SyncMode == SyncAlways {
+		if err := s.file.Sync(); err != nil {
+			return s.rollback(offset, err)
+		}
+		return nil
+	}
+	s.dirty = true
+
+	return nil
+}
+
+// rollback cuts the log back to offset after a failed append, so a torn
+// record never sits in front of the records appended after it
+func (s *FileStore) rollback(offset int64, cause error) error {
+	if err := s.file.Truncate(offset); err != nil {
+		return fmt.Errorf("%w (truncating the torn record: %w)", cause, err)
+	}
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("%w (seeking back to the end of the log: %w)", cause, err)
+	}
+	return cause
+}
+
+// Replace writes blocks to a new log next to the current one and renames it
+// over the current log once synced, so a crash leaves one of the two logs whole
+func (s *FileStore) Replace(blocks []Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	path := s.file.Name()
+	file, err := os.OpenFile(path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := writeLog(file, blocks); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return err
+	}
+
+	// The new log is in place: appends go to it even if the rename is not durable yet
+	previous := s.file
+	s.file = file
+	s.dirty = false
+	_ = previous.Close()
+
+	return syncDir(filepath.Dir(path))
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	stop, done := s.stop, s.done
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	if err := s.file.Sync(); err != nil {
+		_ = s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// encodeRecord frames the JSON encoding of block as a log record
+func encodeRecord(block Block) ([]byte, error) {
+	payload, err := json.Marshal(block)
+	if err != nil {
+		return nil, err
+	}
+
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	return append(record, payload...), nil
+}
+
+// writeLog writes a complete log holding blocks to file and syncs it,
+// leaving the file positioned for further appends
+func writeLog(file *os.File, blocks []Block) error {
+	w := bufio.NewWriter(file)
+	if _, err := w.WriteString(fileStoreMagic); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		record, err := encodeRecord(block)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (s *FileStore) ensureHeader() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// A file shorter than the header was never fully initialised
+	if info.Size() < int64(len(fileStoreMagic)) {
+		if err := s.file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := s.file.WriteAt([]byte(fileStoreMagic), 0); err != nil {
+			return err
+		}
+		return s.file.Sync()
+	}
+
+	magic := make([]byte, len(fileStoreMagic))
+	if _, err := s.file.ReadAt(magic, 0); err != nil {
+		return err
+	}
+	if string(magic) != fileStoreMagic {
+		return fmt.Errorf("%s is not a block log (magic %q)", s.file.Name(), magic)
+	}
+
+	return nil
+}
+
+// readRecords decodes records until the end of the file or the first record
+// that is incomplete or fails its checksum, returning the offset where the
+// valid part of the log ends. A record that passes its checksum but does not
+// decode was written that way, so it is reported rather than truncated.
+func (s *FileStore) readRecords() ([]Block, int64, error) {
+	offset := int64(len(fileStoreMagic))
+	if _, err := s.file.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	blocks := make([]Block, 0)
+	header := make([]byte, recordHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(s.file, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return blocks, offset, nil
+			}
+			return nil, 0, err
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		checksum := binary.BigEndian.Uint32(header[4:8])
+		if size > maxRecordSize {
+			return blocks, offset, nil
+		}
+
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(s.file, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return blocks, offset, nil
+			}
+			return nil, 0, err
+		}
+
+		if crc32.Checksum(payload, crcTable) != checksum {
+			return blocks, offset, nil
+		}
+
+		var block Block
+		if err := json.Unmarshal(payload, &block); err != nil {
+			return nil, 0, fmt.Errorf("%s: corrupted record at offset %d: %w", s.file.Name(), offset, err)
+		}
+
+		blocks = append(blocks, block)
+		offset += int64(recordHeaderSize) + int64(size)
+	}
+}
+
+func (s *FileStore) truncateTail(validSize int64) error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > validSize {
+		log.Printf("block store: truncating %d bytes of incomplete or corrupted records from %s",
+			info.Size()-validSize, s.file.Name())
+
+		if err := s.file.Truncate(validSize); err != nil {
+			return err
+		}
+		if err := s.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.file.Seek(validSize, io.SeekStart)
+	return err
+}
+
+func (s *FileStore) syncLoop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.options.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.dirty {
+				if err := s.file.Sync(); err != nil {
+					log.Printf("block store: fsync failed: %v", err)
+				} else {
+					s.dirty = false
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package domain
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreTruncatesDamagedTail(t *testing.T) {
+	blocks := []Block{
+		{Index: 0, Data: "genesis"},
+		{Index: 1, Data: "first"},
+		{Index: 2, Data: "second"},
+	}
+	last, err := encodeRecord(blocks[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// damage changes the log holding blocks
+		damage     func(t *testing.T, path string)
+		wantBlocks int
+	}{
+		{
+			name:       "intact log",
+			damage:     func(*testing.T, string) {},
+			wantBlocks: 3,
+		},
+		{
+			name: "torn record header",
+			damage: func(t *testing.T, path string) {
+				appendBytes(t, path, []byte{0, 0, 1})
+			},
+			wantBlocks: 3,
+		},
+		{
+			name: "torn record payload",
+			damage: func(t *testing.T, path string) {
+				appendBytes(t, path, last[:len(last)-5])
+			},
+			wantBlocks: 3,
+		},
+		{
+			name: "checksum mismatch on the last record",
+			damage: func(t *testing.T, path string) {
+				flipLastByte(t, path)
+			},
+			wantBlocks: 2,
+		},
+		{
+			name: "record longer than the size limit",
+			damage: func(t *testing.T, path string) {
+				header := make([]byte, recordHeaderSize)
+				binary.BigEndian.PutUint32(header, maxRecordSize+1)
+				appendBytes(t, path, header)
+			},
+			wantBlocks: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeStore(t, dir, blocks...)
+			tt.damage(t, filepath.Join(dir, fileStoreName))
+
+			store := openStore(t, dir)
+			loaded, err := store.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			assertBlocks(t, loaded, blocks[:tt.wantBlocks])
+
+			// Appends after recovery must land right after the last valid record
+			next := Block{Index: tt.wantBlocks, Data: "after recovery"}
+			if err := store.Append(next); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+			if err := store.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			reloaded, err := openStore(t, dir).Load()
+			if err != nil {
+				t.Fatalf("reload: %v", err)
+			}
+			assertBlocks(t, reloaded, append(blocks[:tt.wantBlocks:tt.wantBlocks], next))
+		})
+	}
+}
+
+func TestFileStoreReportsUndecodableRecord(t *testing.T) {
+	dir := t.TempDir()
+	writeStore(t, dir, Block{Index: 0, Data: "genesis"})
+
+	// A record that passes its checksum was written whole, so it is not a torn tail
+	payload := []byte(`{"index":"not a number"}`)
+	record := make([]byte, recordHeaderSize, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.Checksum(payload, crcTable))
+	path := filepath.Join(dir, fileStoreName)
+	appendBytes(t, path, append(record, payload...))
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = openStore(t, dir).Load()
+	if err == nil || !strings.Contains(err.Error(), "corrupted record") {
+		t.Fatalf("Load error = %v, want a corrupted record error", err)
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Fatalf("log size changed from %d to %d bytes", before.Size(), after.Size())
+	}
+}
+
+func TestFileStoreRejectsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, fileStoreName), []byte("not a block log"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := openStore(t, dir).Load(); err == nil || !strings.Contains(err.Error(), "not a block log") {
+		t.Fatalf("Load error = %v, want a magic mismatch", err)
+	}
+}
+
+func TestFileStoreClosed(t *testing.T) {
+	store := openStore(t, t.TempDir())
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Append(Block{}); !errors.Is(err, ErrStoreClosed) {
+		t.Fatalf("Append error = %v, want %v", err, ErrStoreClosed)
+	}
+	if _, err := store.Load(); !errors.Is(err, ErrStoreClosed) {
+		t.Fatalf("Load error = %v, want %v", err, ErrStoreClosed)
+	}
+}
+
+func openStore(t *testing.T, dir string) *FileStore {
+	t.Helper()
+	store, err := OpenFileStore(FileStoreOptions{Dir: dir, SyncMode: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// writeStore creates a log in dir holding blocks
+func writeStore(t *testing.T, dir string, blocks ...Block) {
+	t.Helper()
+	store := openStore(t, dir)
+	if _, err := store.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Append(blocks...); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func appendBytes(t *testing.T, path string, data []byte) {
+	t.Helper()
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func flipLastByte(t *testing.T, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertBlocks(t *testing.T, got, want []Block) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d blocks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Index != want[i].Index || got[i].Data != want[i].Data {
+			t.Fatalf("block %d = %d %q, want %d %q", i, got[i].Index, got[i].Data, want[i].Index, want[i].Data)
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+const (
+	// BlockExtended means the block became the tip of the canonical chain
+	BlockExtended SubmitStatus = "extended"
+	// BlockSideBranch means the block was stored on a branch with less work
+	BlockSideBranch SubmitStatus = "side_branch"
+	// BlockReorg means the block made its branch the heaviest and canonical
+	BlockReorg SubmitStatus = "reorg"
+)
+
+var (
+	ErrBlockExists   = errors.New("block already known")
+	ErrUnknownParent = errors.New("parent block not found")
+	ErrInvalidBlock  = errors.New("invalid block")
+)
+
+type (
+	// blockNode is a block of the tree of known branches. work is the
+	// cumulative expected number of hashes from genesis to this block.
+	blockNode struct {
+		block  Block
+		parent *blockNode
+		work   float64
+	}
+
+	// sideLedger is the ledger after the last block stored on a side
+	// branch. Syncing a fork submits its blocks one after the other, and each
+	// one builds on the previous: keeping the ledger of the branch tip turns
+	// the replay from genesis for every block into one transfer check per block.
+	sideLedger struct {
+		hash   Hash
+		ledger *Ledger
+	}
+
+	SubmitStatus string
+
+	// ForkChoice reports where a new block landed and, after a reorg, the
+	// canonical blocks it replaced
+	ForkChoice struct {
+		Status     SubmitStatus `json:"status"`
+		TipHash    Hash         `json:"tip_hash"`
+		Height     int          `json:"height"`
+		TotalWork  float64      `json:"total_work"`
+		ForkHeight int          `json:"fork_height,omitempty"`
+		ReorgDepth int          `json:"reorg_depth,omitempty"`
+		Orphaned   []Block      `json:"orphaned,omitempty"`
+	}
+
+	// ChainTip is the last block of a known branch
+	ChainTip struct {
+		Hash      Hash    `json:"hash"`
+		Height    int     `json:"height"`
+		TotalWork float64 `json:"total_work"`
+		Active    bool    `json:"active"`
+		// BranchLength is the number of blocks since the branch left the
+		// canonical chain (0 for the active tip)
+		BranchLength int `json:"branch_length"`
+	}
+
+	// InvalidBlockError lists the validation failures of a submitted block
+	InvalidBlockError struct {
+		Errors []ValidationError
+	}
+)
+
+func (e *InvalidBlockError) Error() string {
+	first := e.Errors[0]
+	return fmt.Sprintf("%s: %s: %s", ErrInvalidBlock, first.Code, first.Reason)
+}
+
+func (e *InvalidBlockError) Unwrap() error {
+	return ErrInvalidBlock
+}
+
+// work is the expected number of hashes needed to mine block
+func (p Params) work(block Block) float64 {
+	return p.Mode.ExpectedHashes(p.effectiveDifficulty(block))
+}
+
+// loadTree rebuilds the block tree from blocks in store order, where every
+// parent precedes its children, and returns the heaviest tip. Ties go to the
+// branch seen first.
+func (bc *Blockchain) loadTree(blocks []Block) (*blockNode, error) {
+	var tip *blockNode
+	for _, block := range blocks {
+		if _, ok := bc.nodes[block.Hash]; ok {
+			continue
+		}
+
+		parent, ok := bc.nodes[block.PreviousHash]
+		if !ok && block.Index != 0 {
+			return nil, fmt.Errorf("%w: block %d (%s) has unknown parent %s", ErrInvalidStoredChain, block.Index, block.Hash, block.PreviousHash)
+		}
+		if ok && block.Index == 0 {
+			return nil, fmt.Errorf("%w: genesis block %s has a parent", ErrInvalidStoredChain, block.Hash)
+		}
+		if !ok && tip != nil {
+			return nil, fmt.Errorf("%w: second genesis block %s", ErrInvalidStoredChain, block.Hash)
+		}
+
+		node := bc.addNode(block, parent)
+		if tip == nil || node.work > tip.work {
+			tip = node
+		}
+	}
+	return tip, nil
+}
+
+// addNode records block in the tree. Callers must hold bc.mu.
+func (bc *Blockchain) addNode(block Block, parent *blockNode) *blockNode {
+	node := &blockNode{block: block, parent: parent, work: bc.params.work(block)}
+	if parent != nil {
+		node.work += parent.work
+	}
+	bc.nodes[block.Hash] = node
+	return node
+}
+
+// branch returns the blocks from genesis to node. Callers must hold bc.mu.
+func (bc *Blockchain) branch(node *blockNode) []Block {
+	if node == bc.tip {
+		return bc.chain
+	}
+
+	blocks := make([]Block, node.block.Index+1)
+	for n := node; n != nil; n = n.parent {
+		blocks[n.block.Index] = n.block
+	}
+	return blocks
+}
+
+// branchLedger returns the ledger state at node. The canonical ledger is
+// returned as is for the tip and the side ledger for the last block stored
+// on a side branch; other branches are replayed from genesis once.
+// Callers must hold bc.mu and must not change the ledger.
+func (bc *Blockchain) branchLedger(node *blockNode, branch []Block) (*Ledger, error) {
+	switch {
+	case node == bc.tip:
+		return bc.ledger, nil
+	case bc.side.ledger != nil && bc.side.hash == node.block.Hash:
+		return bc.side.ledger, nil
+	}
+	return replayLedger(branch, bc.params.BlockReward)
+}
+
+// SubmitBlock adds a block mined elsewhere. It is validated against the
+// branch of its parent, which may be any known block, and becomes canonical
+// when its branch has more cumulative work than the current tip.
+func (bc *Blockchain) SubmitBlock(block Block) (ForkChoice, error) {
+	if block.Version != CurrentEncoding {
+		return ForkChoice{}, &InvalidBlockError{Errors: []ValidationError{{
+			Index:  block.Index,
+			Code:   ReasonInvalidEncoding,
+			Reason: fmt.Sprintf("submitted blocks must use encoding version %d, got %d", CurrentEncoding, block.Version),
+		}}}
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	return bc.insertBlock(block)
+}
+
+// MineOn mines a block on top of any known block instead of the tip, then
+// submits it like an external block. It is the way to create forks on purpose.
+func (bc *Blockchain) MineOn(ctx context.Context, parentHash Hash, payload Payload) (Block, ForkChoice, MiningStats, error) {
+	if err := payload.verify(); err != nil {
+		return Block{}, ForkChoice{}, MiningStats{}, err
+	}
+
+	lockWait := bc.lockTimed()
+	defer bc.mu.Unlock()
+	stats := MiningStats{LockWaitMs: milliseconds(lockWait)}
+
+	parent, ok := bc.nodes[parentHash]
+	if !ok {
+		return Block{}, ForkChoice{}, stats, fmt.Errorf("%w: %s", ErrUnknownParent, parentHash)
+	}
+
+	branch := bc.branch(parent)
+	ledger, err := bc.branchLedger(parent, branch)
+	if err != nil {
+		return Block{}, ForkChoice{}, stats, err
+	}
+
+	block, err := bc.nextBlockOn(branch, ledger, payload)
+	if err != nil {
+		return Block{}, ForkChoice{}, stats, err
+	}
+	stats, err = bc.mineBlock(ctx, &block, payload.Mining)
+	stats.LockWaitMs = milliseconds(lockWait)
+	if err != nil {
+		return Block{}, ForkChoice{}, stats, err
+	}
+
+	choice, err := bc.insertBlock(block)
+	return block, choice, stats, err
+}
+
+// insertBlock validates block against the branch of its parent, persists it
+// and runs the fork choice. Callers must hold bc.mu.
+func (bc *Blockchain) insertBlock(block Block) (ForkChoice, error) {
+	parent, ok := bc.nodes[block.PreviousHash]
+	if !ok {
+		return ForkChoice{}, fmt.Errorf("%w: %s", ErrUnknownParent, block.PreviousHash)
+	}
+
+	branch := bc.branch(parent)
+	if errs := validateBlock(len(branch), block, branch, bc.params); len(errs) > 0 {
+		return ForkChoice{}, &InvalidBlockError{Errors: errs}
+	}
+
+	// Checked after validation so a tampered copy of a known block is reported as invalid
+	if _, ok := bc.nodes[block.Hash]; ok {
+		return ForkChoice{}, fmt.Errorf("%w: %s", ErrBlockExists, block.Hash)
+	}
+
+	if parent == bc.tip {
+		if err := bc.appendBlock(block); err != nil {
+			var txErr *TransactionError
+			if errors.As(err, &txErr) {
+				return ForkChoice{}, ledgerViolation(block, txErr)
+			}
+			return ForkChoice{}, err
+		}
+		return bc.forkChoice(BlockExtended), nil
+	}
+
+	ledger, err := bc.branchLedger(parent, branch)
+	if err != nil {
+		return ForkChoice{}, err
+	}
+	touched, err := ledger.replay(block, bc.params.BlockReward)
+	if err != nil {
+		return ForkChoice{}, ledgerViolation(block, err)
+	}
+
+	if err := bc.store.Append(block); err != nil {
+		return ForkChoice{}, fmt.Errorf("persisting block %d: %w", block.Index, err)
+	}
+	node := bc.addNode(block, parent)
+	bc.notify(block)
+
+	// ledger is not the canonical one, so it becomes the state after block
+	ledger.commit(touched)
+	if node.work <= bc.tip.work {
+		bc.side = sideLedger{hash: block.Hash, ledger: ledger}
+		return bc.forkChoice(BlockSideBranch), nil
+	}
+
+	// The branch losing the tip keeps its ledger in case it is extended again
+	bc.side = sideLedger{hash: bc.tip.block.Hash, ledger: bc.ledger}
+	return bc.reorg(node, append(slices.Clip(branch), block), ledger), nil
+}
+
+// ledgerViolation reports a block whose transfers do not apply to the ledger
+// of its branch
+func ledgerViolation(block Block, err error) *InvalidBlockError {
+	return &InvalidBlockError{Errors: []ValidationError{{
+		Index:  block.Index,
+		Code:   ReasonLedgerViolation,
+		Reason: err.Error(),
+	}}}
+}
+
+// reorg makes the branch ending at node canonical. Transactions of the
+// orphaned blocks that the new branch does not include go back to the
+// mempool, ahead of the pending ones, when they are still valid. Callers
+// must hold bc.mu.
+func (bc *Blockchain) reorg(node *blockNode, chain []Block, ledger *Ledger) ForkChoice {
+	fork := 0
+	for fork < len(bc.chain) && fork < len(chain) && bc.chain[fork].Hash == chain[fork].Hash {
+		fork++
+	}
+	orphaned := slices.Clone(bc.chain[fork:])
+
+	bc.chain = chain
+	bc.ledger = ledger
+	bc.tip = node
+	bc.reorgs++
+
+	// The pool is rebuilt rather than topped up: a pending transfer may
+	// follow an orphaned one of the same sender, which has to go back first
+	var restored []Transaction
+	for _, block := range orphaned {
+		for _, tx := range block.Transactions {
+			if tx.Signed() {
+				restored = append(restored, tx)
+			}
+		}
+	}
+	bc.mempool.readmit(restored, chain[fork:], bc.ledger)
+
+	choice := bc.forkChoice(BlockReorg)
+	choice.ForkHeight = fork - 1
+	choice.ReorgDepth = len(orphaned)
+	choice.Orphaned = orphaned
+	return choice
+}
+
+// forkChoice describes the canonical tip. Callers must hold bc.mu.
+func (bc *Blockchain) forkChoice(status SubmitStatus) ForkChoice {
+	return ForkChoice{
+		Status:    status,
+		TipHash:   bc.tip.block.Hash,
+		Height:    bc.tip.block.Index,
+		TotalWork: bc.tip.work,
+	}
+}
+
+// Tips returns the last block of every known branch, the active one first
+func (bc *Blockchain) Tips() []ChainTip {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	hasChild := make(map[Hash]bool, len(bc.nodes))
+	for _, node := range bc.nodes {
+		if node.parent != nil {
+			hasChild[node.parent.block.Hash] = true
+		}
+	}
+
+	tips := make([]ChainTip, 0)
+	for hash, node := range bc.nodes {
+		if hasChild[hash] {
+			continue
+		}
+		tip := ChainTip{
+			Hash:      hash,
+			Height:    node.block.Index,
+			TotalWork: node.work,
+			Active:    node == bc.tip,
+		}
+		for n := node; n != nil && !bc.isCanonical(n); n = n.parent {
+			tip.BranchLength++
+		}
+		tips = append(tips, tip)
+	}
+
+	slices.SortFunc(tips, func(a, b ChainTip) int {
+		switch {
+		case a.Active != b.Active:
+			if a.Active {
+				return -1
+			}
+			return 1
+		case a.TotalWork != b.TotalWork:
+			if a.TotalWork > b.TotalWork {
+				return -1
+			}
+			return 1
+		default:
+			return b.Height - a.Height
+		}
+	})
+	return tips
+}
+
+// isCanonical reports whether node is part of the canonical chain. Callers must hold bc.mu.
+func (bc *Blockchain) isCanonical(node *blockNode) bool {
+	index := node.block.Index
+	return index < len(bc.chain) && bc.chain[index].Hash == node.block.Hash
+}
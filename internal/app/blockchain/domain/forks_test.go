@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestForkChoiceFollowsMostWork(t *testing.T) {
+	const (
+		minerA = "aaaa000000000000000000000000000000000000"
+		minerB = "bbbb000000000000000000000000000000000000"
+	)
+
+	// Every block has the same difficulty, so the most work is the longest
+	// branch and a tie keeps the branch that was canonical first
+	steps := []struct {
+		name      string
+		parent    string
+		miner     string
+		wantState SubmitStatus
+		wantTip   string
+		wantDepth int
+		// wantBalances are the canonical ledger balances after the step
+		wantBalances map[string]uint64
+	}{
+		{
+			name: "a1", parent: "genesis", miner: minerA,
+			wantState: BlockExtended, wantTip: "a1",
+			wantBalances: map[string]uint64{minerA: 50, minerB: 0},
+		},
+		{
+			name: "b1", parent: "genesis", miner: minerB,
+			wantState: BlockSideBranch, wantTip: "a1",
+			wantBalances: map[string]uint64{minerA: 50, minerB: 0},
+		},
+		{
+			name: "b2", parent: "b1", miner: minerB,
+			wantState: BlockReorg, wantTip: "b2", wantDepth: 1,
+			wantBalances: map[string]uint64{minerA: 0, minerB: 100},
+		},
+		{
+			name: "a2", parent: "a1", miner: minerA,
+			wantState: BlockSideBranch, wantTip: "b2",
+			wantBalances: map[string]uint64{minerA: 0, minerB: 100},
+		},
+		{
+			name: "a3", parent: "a2", miner: minerA,
+			wantState: BlockReorg, wantTip: "a3", wantDepth: 2,
+			wantBalances: map[string]uint64{minerA: 150, minerB: 0},
+		},
+		{
+			name: "a4", parent: "a3", miner: minerA,
+			wantState: BlockExtended, wantTip: "a4",
+			wantBalances: map[string]uint64{minerA: 200, minerB: 0},
+		},
+	}
+
+	bc := newTestChain(t)
+	hashes := map[string]Hash{"genesis": bc.Genesis().Hash}
+	names := map[Hash]string{bc.Genesis().Hash: "genesis"}
+
+	for _, step := range steps {
+		block, choice, _, err := bc.MineOn(context.Background(), hashes[step.parent], Payload{Data: step.name, Miner: step.miner})
+		if err != nil {
+			t.Fatalf("%s: MineOn: %v", step.name, err)
+		}
+		hashes[step.name] = block.Hash
+		names[block.Hash] = step.name
+
+		if choice.Status != step.wantState || names[choice.TipHash] != step.wantTip || choice.ReorgDepth != step.wantDepth {
+			t.Fatalf("%s: %s to tip %s with depth %d, want %s to tip %s with depth %d",
+				step.name, choice.Status, names[choice.TipHash], choice.ReorgDepth, step.wantState, step.wantTip, step.wantDepth)
+		}
+		for address, want := range step.wantBalances {
+			if got := bc.Account(address).Balance; got != want {
+				t.Fatalf("%s: balance of %s = %d, want %d", step.name, address, got, want)
+			}
+		}
+	}
+
+	// The canonical chain and its ledger match a replay from genesis
+	if report := bc.Validate(); !report.Valid {
+		t.Fatalf("canonical chain failed validation: %+v", report.Errors)
+	}
+	ledger, err := replayLedger(bc.Chain(), bc.Params().BlockReward)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, address := range []string{minerA, minerB} {
+		if got, want := bc.Account(address), ledger.Account(address); got != want {
+			t.Errorf("account %s = %+v, replay gives %+v", address, got, want)
+		}
+	}
+
+	tips := bc.Tips()
+	if len(tips) != 2 || names[tips[0].Hash] != "a4" || !tips[0].Active || names[tips[1].Hash] != "b2" || tips[1].BranchLength != 2 {
+		t.Fatalf("tips = %+v, want a4 active and b2 two blocks off the chain", tips)
+	}
+}
+
+func TestReorgRestoresOrphanedTransfersInNonceOrder(t *testing.T) {
+	ctx := context.Background()
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	sender := AddressFromPublicKey(key.Public().(ed25519.PublicKey))
+	bc := newTestChain(t)
+
+	mineOn := func(parent Hash, payload Payload) Block {
+		t.Helper()
+		block, _, _, err := bc.MineOn(ctx, parent, payload)
+		if err != nil {
+			t.Fatalf("MineOn: %v", err)
+		}
+		return block
+	}
+	submit := func(nonce uint64) Transaction {
+		t.Helper()
+		tx := SignTransaction(Transaction{To: bob, Amount: 5, Fee: 1, Nonce: nonce, Timestamp: time.Now()}, key)
+		if _, err := bc.SubmitTransaction(tx); err != nil {
+			t.Fatalf("SubmitTransaction(%d): %v", nonce, err)
+		}
+		return tx
+	}
+
+	funded := mineOn(bc.Genesis().Hash, Payload{Data: "fund", Miner: sender})
+	tx0 := submit(0)
+	mineOn(funded.Hash, Payload{FromMempool: true})
+	// tx1 waits in the pool while tx0 sits in a block about to be orphaned
+	tx1 := submit(1)
+
+	fork := mineOn(funded.Hash, Payload{Data: "fork"})
+	mineOn(fork.Hash, Payload{Data: "heavier"})
+
+	var pending []string
+	for _, tx := range bc.Mempool().Transactions {
+		pending = append(pending, tx.ID)
+	}
+	if !slices.Contains(pending, tx0.ID) || !slices.Contains(pending, tx1.ID) {
+		t.Fatalf("pending = %v, want both %s and %s", pending, tx0.ID, tx1.ID)
+	}
+	if account, next := bc.Account(sender), bc.NextNonce(sender); account.Nonce != 0 || next != 2 {
+		t.Fatalf("ledger nonce %d and next nonce %d, want 0 and 2", account.Nonce, next)
+	}
+
+	block, _, err := bc.AddBlock(ctx, Payload{FromMempool: true})
+	if err != nil {
+		t.Fatalf("mining the restored transfers: %v", err)
+	}
+	if len(block.Transactions) != 2 || bc.Account(sender).Nonce != 2 {
+		t.Fatalf("mined %d transactions, sender nonce %d; want 2 and 2", len(block.Transactions), bc.Account(sender).Nonce)
+	}
+}
+
+func TestSubmitBlockReportsLedgerViolations(t *testing.T) {
+	// Same genesis, but its coinbases mint twice the reward of the node's
+	generous := newTestChain(t, WithMiner(miner), WithBlockReward(2*DefaultBlockReward))
+	inflated := mineTransactions(t, generous, 1)
+
+	tests := []struct {
+		name string
+		// extendTip mines a block first, so that inflated lands on a side branch
+		extendTip bool
+	}{
+		{name: "extending the tip"},
+		{name: "on a side branch", extendTip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestChain(t)
+			if tt.extendTip {
+				mineTransactions(t, bc, 1)
+			}
+
+			_, err := bc.SubmitBlock(inflated)
+			var blockErr *InvalidBlockError
+			if !errors.Is(err, ErrInvalidBlock) || !errors.As(err, &blockErr) {
+				t.Fatalf("SubmitBlock error = %v, want an invalid block", err)
+			}
+			if code := blockErr.Errors[0].Code; code != ReasonLedgerViolation {
+				t.Fatalf("code = %s, want %s", code, ReasonLedgerViolation)
+			}
+			if bc.HasBlock(inflated.Hash) {
+				t.Fatal("the rejected block was added to the tree")
+			}
+		})
+	}
+}
+
+func TestSubmitBlockRejections(t *testing.T) {
+	bc := newTestChain(t)
+	block := mineTransactions(t, bc, 1)
+
+	tests := []struct {
+		name    string
+		change  func(block *Block)
+		wantErr error
+	}{
+		{
+			name:    "known block",
+			change:  func(*Block) {},
+			wantErr: ErrBlockExists,
+		},
+		{
+			name:    "unknown parent",
+			change:  func(block *Block) { block.PreviousHash = ZeroHash },
+			wantErr: ErrUnknownParent,
+		},
+		{
+			name:    "tampered copy of a known block",
+			change:  func(block *Block) { block.Data = "rewritten" },
+			wantErr: ErrInvalidBlock,
+		},
+		{
+			name:    "legacy encoding",
+			change:  func(block *Block) { block.Version = EncodingV2 },
+			wantErr: ErrInvalidBlock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submitted := block
+			tt.change(&submitted)
+			if _, err := bc.SubmitBlock(submitted); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SubmitBlock error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
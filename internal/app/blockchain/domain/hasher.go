@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+)
+
+const (
+	HashSHA256       HashAlgorithm = "sha256"
+	HashSHA512_256   HashAlgorithm = "sha512/256"
+	HashSHA3_256     HashAlgorithm = "sha3-256"
+	HashDoubleSHA256 HashAlgorithm = "double-sha256"
+
+	// DefaultHashAlgorithm is used by chains created without WithHashAlgorithm
+	// and by stored chains whose genesis block predates the choice
+	DefaultHashAlgorithm = HashSHA256
+)
+
+var ErrInvalidHashAlgorithm = errors.New("invalid hash algorithm")
+
+type (
+	// HashAlgorithm names the digest block headers are hashed with
+	HashAlgorithm string
+
+	// Hasher computes the proof-of-work digest of an encoded header. Every
+	// implementation produces HashSize bytes so difficulty, work and hash
+	// encoding are the same whatever the algorithm.
+	Hasher interface {
+		Algorithm() HashAlgorithm
+		Sum(data []byte) [HashSize]byte
+	}
+
+	sha256Hasher       struct{}
+	sha512_256Hasher   struct{}
+	sha3_256Hasher     struct{}
+	doubleSHA256Hasher struct{}
+)
+
+// HashAlgorithms lists the supported algorithms
+func HashAlgorithms() []HashAlgorithm {
+	return []HashAlgorithm{HashSHA256, HashSHA512_256, HashSHA3_256, HashDoubleSHA256}
+}
+
+func ParseHashAlgorithm(value string) (HashAlgorithm, error) {
+	if _, err := NewHasher(HashAlgorithm(value)); err != nil {
+		return "", err
+	}
+	return HashAlgorithm(value), nil
+}
+
+// NewHasher returns the hasher of algorithm. The empty algorithm, found in
+// genesis blocks created before the choice existed, is DefaultHashAlgorithm.
+func NewHasher(algorithm HashAlgorithm) (Hasher, error) {
+	switch algorithm {
+	case HashSHA256, "":
+		return sha256Hasher{}, nil
+	case HashSHA512_256:
+		return sha512_256Hasher{}, nil
+	case HashSHA3_256:
+		return sha3_256Hasher{}, nil
+	case HashDoubleSHA256:
+		return doubleSHA256Hasher{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q (expected one of %v)", ErrInvalidHashAlgorithm, algorithm, HashAlgorithms())
+	}
+}
+
+func (sha256Hasher) Algorithm() HashAlgorithm { return HashSHA256 }
+
+func (sha256Hasher) Sum(data []byte) [HashSize]byte {
+	return sha256.Sum256(data)
+}
+
+func (sha512_256Hasher) Algorithm() HashAlgorithm { return HashSHA512_256 }
+
+// Sum computes SHA-512 with its own initial values, truncated to 256 bits.
+// It is usually faster than SHA-256 on 64-bit CPUs without SHA extensions.
+func (sha512_256Hasher) Sum(data []byte) [HashSize]byte {
+	return sha512.Sum512_256(data)
+}
+
+func (sha3_256Hasher) Algorithm() HashAlgorithm { return HashSHA3_256 }
+
+func (sha3_256Hasher) Sum(data []byte) [HashSize]byte {
+	return sha3.Sum256(data)
+}
+
+func (doubleSHA256Hasher) Algorithm() HashAlgorithm { return HashDoubleSHA256 }
+
+// Sum computes SHA-256(SHA-256(data)), as Bitcoin does, at twice the cost per hash
+func (doubleSHA256Hasher) Sum(data []byte) [HashSize]byte {
+	first := sha256.Sum256(data)
+	return sha256.Sum256(first[:])
+}
@@ -0,0 +1,197 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultBlockReward is the amount minted by the coinbase of every block
+const DefaultBlockReward uint64 = 50
+
+type (
+	// Account is the state of an address: its balance and the nonce its next
+	// transfer must carry
+	Account struct {
+		Address string `json:"address"`
+		Balance uint64 `json:"balance"`
+		Nonce   uint64 `json:"nonce"`
+	}
+
+	// Ledger holds the account balances derived by replaying the transfers of
+	// the chain. It is not safe for concurrent use; the Blockchain guards it
+	// with its own lock.
+	Ledger struct {
+		accounts map[string]Account
+	}
+
+	// ledgerView stages account changes on top of a ledger without touching it
+	ledgerView struct {
+		base    *Ledger
+		touched map[string]Account
+	}
+)
+
+func newLedger() *Ledger {
+	return &Ledger{
+		accounts: make(map[string]Account),
+	}
+}
+
+// replayLedger rebuilds the ledger from scratch
+func replayLedger(chain []Block, reward uint64) (*Ledger, error) {
+	ledger := newLedger()
+	for _, block := range chain {
+		if err := ledger.apply(block, reward); err != nil {
+			return nil, fmt.Errorf("block %d: %w", block.Index, err)
+		}
+	}
+	return ledger, nil
+}
+
+// Account returns the state of address. Unknown addresses have a zero balance.
+func (l *Ledger) Account(address string) Account {
+	account, ok := l.accounts[address]
+	if !ok {
+		return Account{Address: address}
+	}
+	return account
+}
+
+// Len returns the number of accounts that ever received funds
+func (l *Ledger) Len() int {
+	return len(l.accounts)
+}
+
+// check reports whether block can be applied, without changing the ledger
+func (l *Ledger) check(block Block, reward uint64) error {
+	_, err := l.replay(block, reward)
+	return err
+}
+
+// apply commits the transfers of block. The ledger is unchanged on error.
+func (l *Ledger) apply(block Block, reward uint64) error {
+	touched, err := l.replay(block, reward)
+	if err != nil {
+		return err
+	}
+	l.commit(touched)
+	return nil
+}
+
+func (l *Ledger) commit(touched map[string]Account) {
+	for address, account := range touched {
+		l.accounts[address] = account
+	}
+}
+
+func (l *Ledger) view() *ledgerView {
+	return &ledgerView{base: l, touched: make(map[string]Account)}
+}
+
+// replay returns the accounts touched by block after its transfers. Only the
+// first transaction may be a coinbase and it must mint exactly the reward
+// plus the fees of the block; every signed transfer must carry the sender's
+// next nonce, which rejects replays and double spends.
+func (l *Ledger) replay(block Block, reward uint64) (map[string]Account, error) {
+	fees, err := blockFees(block.Transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	view := l.view()
+	for i, tx := range block.Transactions {
+		if !tx.IsTransfer() {
+			continue
+		}
+
+		if !tx.IsCoinbase() {
+			if txErr := view.transfer(i, tx); txErr != nil {
+				return nil, txErr
+			}
+			continue
+		}
+
+		switch {
+		case i != 0:
+			return nil, &TransactionError{TxIndex: i, TxID: tx.ID, Code: TxInvalidCoinbase, Reason: "coinbase must be the first transaction"}
+		case reward > math.MaxUint64-fees || tx.Amount != reward+fees:
+			return nil, &TransactionError{TxIndex: i, TxID: tx.ID, Code: TxInvalidCoinbase, Reason: fmt.Sprintf("coinbase amount %d, block reward is %d plus %d in fees", tx.Amount, reward, fees)}
+		case tx.Nonce != uint64(block.Index):
+			return nil, &TransactionError{TxIndex: i, TxID: tx.ID, Code: TxInvalidCoinbase, Reason: fmt.Sprintf("coinbase nonce %d, expected the block index %d", tx.Nonce, block.Index)}
+		}
+		if txErr := view.credit(i, tx); txErr != nil {
+			return nil, txErr
+		}
+	}
+
+	return view.touched, nil
+}
+
+// blockFees sums the fees paid by the signed transfers of a block
+func blockFees(txs []Transaction) (uint64, error) {
+	var fees uint64
+	for i, tx := range txs {
+		if tx.IsCoinbase() {
+			continue
+		}
+		if tx.Fee > math.MaxUint64-fees {
+			return 0, &TransactionError{TxIndex: i, TxID: tx.ID, Code: TxInvalidTransfer, Reason: "block fees overflow"}
+		}
+		fees += tx.Fee
+	}
+	return fees, nil
+}
+
+func (v *ledgerView) account(address string) Account {
+	if account, ok := v.touched[address]; ok {
+		return account
+	}
+	return v.base.Account(address)
+}
+
+// transfer applies a signed transfer at position index of its block. The
+// view is unchanged on error.
+func (v *ledgerView) transfer(index int, tx Transaction) *TransactionError {
+	sender := v.account(tx.From)
+	cost, ok := tx.Cost()
+
+	switch {
+	case tx.Nonce != sender.Nonce:
+		return &TransactionError{TxIndex: index, TxID: tx.ID, Code: TxInvalidNonce, Reason: fmt.Sprintf("nonce %d, expected %d", tx.Nonce, sender.Nonce)}
+	case !ok || cost > sender.Balance:
+		return &TransactionError{TxIndex: index, TxID: tx.ID, Code: TxInsufficientFunds, Reason: fmt.Sprintf("amount %d plus fee %d exceeds balance %d", tx.Amount, tx.Fee, sender.Balance)}
+	}
+
+	// A transfer to self only costs the fee and cannot overflow
+	if tx.To != tx.From && tx.Amount > math.MaxUint64-v.account(tx.To).Balance {
+		return &TransactionError{TxIndex: index, TxID: tx.ID, Code: TxInvalidTransfer, Reason: "recipient balance overflows"}
+	}
+
+	sender.Balance -= cost
+	sender.Nonce++
+	v.touched[sender.Address] = sender
+
+	recipient := v.account(tx.To)
+	recipient.Balance += tx.Amount
+	v.touched[recipient.Address] = recipient
+
+	return nil
+}
+
+// credit pays a coinbase to its recipient
+func (v *ledgerView) credit(index int, tx Transaction) *TransactionError {
+	recipient := v.account(tx.To)
+	if tx.Amount > math.MaxUint64-recipient.Balance {
+		return &TransactionError{TxIndex: index, TxID: tx.ID, Code: TxInvalidTransfer, Reason: "recipient balance overflows"}
+	}
+	recipient.Balance += tx.Amount
+	v.touched[recipient.Address] = recipient
+	return nil
+}
+
+// Account returns the ledger state of address
+func (bc *Blockchain) Account(address string) Account {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.ledger.Account(address)
+}
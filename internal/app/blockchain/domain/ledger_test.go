@@ -0,0 +1,167 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+const (
+	alice = "a11ce00000000000000000000000000000000000"
+	bob   = "b0b0000000000000000000000000000000000000"
+	miner = "3a1e700000000000000000000000000000000000"
+)
+
+func TestLedgerReplay(t *testing.T) {
+	const reward = 50
+
+	transfer := func(from, to string, amount, fee, nonce uint64) Transaction {
+		return Transaction{ID: "transfer", From: from, To: to, Amount: amount, Fee: fee, Nonce: nonce}
+	}
+	coinbase := func(amount, nonce uint64) Transaction {
+		return Transaction{ID: "coinbase", To: miner, Amount: amount, Nonce: nonce}
+	}
+
+	tests := []struct {
+		name string
+		txs  []Transaction
+		// want is checked when the block applies, wantCode when it is rejected
+		want     map[string]Account
+		wantCode TransactionErrorCode
+		wantTx   int
+	}{
+		{
+			name: "transfer with fee paid to the coinbase",
+			txs:  []Transaction{coinbase(reward+2, 1), transfer(alice, bob, 30, 2, 0)},
+			want: map[string]Account{
+				alice: {Address: alice, Balance: 68, Nonce: 1},
+				bob:   {Address: bob, Balance: 30},
+				miner: {Address: miner, Balance: reward + 2},
+			},
+		},
+		{
+			name: "consecutive nonces from one sender",
+			txs:  []Transaction{transfer(alice, bob, 10, 0, 0), transfer(alice, bob, 10, 0, 1)},
+			want: map[string]Account{
+				alice: {Address: alice, Balance: 80, Nonce: 2},
+				bob:   {Address: bob, Balance: 20},
+			},
+		},
+		{
+			name: "transfer to self only costs the fee",
+			txs:  []Transaction{transfer(alice, alice, 40, 1, 0)},
+			want: map[string]Account{
+				alice: {Address: alice, Balance: 99, Nonce: 1},
+			},
+		},
+		{
+			name: "data transactions do not touch the ledger",
+			txs:  []Transaction{{ID: "data", Payload: "hello"}},
+			want: map[string]Account{
+				alice: {Address: alice, Balance: 100},
+			},
+		},
+		{
+			name:     "nonce from the future",
+			txs:      []Transaction{transfer(alice, bob, 10, 0, 1)},
+			wantCode: TxInvalidNonce,
+		},
+		{
+			name:     "replayed nonce",
+			txs:      []Transaction{transfer(alice, bob, 10, 0, 0), transfer(alice, bob, 10, 0, 0)},
+			wantCode: TxInvalidNonce,
+			wantTx:   1,
+		},
+		{
+			name:     "amount plus fee above the balance",
+			txs:      []Transaction{transfer(alice, bob, 99, 2, 0)},
+			wantCode: TxInsufficientFunds,
+		},
+		{
+			name:     "double spend across two transfers",
+			txs:      []Transaction{transfer(alice, bob, 60, 0, 0), transfer(alice, miner, 60, 0, 1)},
+			wantCode: TxInsufficientFunds,
+			wantTx:   1,
+		},
+		{
+			name:     "sender without funds",
+			txs:      []Transaction{transfer(bob, alice, 1, 0, 0)},
+			wantCode: TxInsufficientFunds,
+		},
+		{
+			name:     "coinbase after a transfer",
+			txs:      []Transaction{transfer(alice, bob, 10, 0, 0), coinbase(reward, 1)},
+			wantCode: TxInvalidCoinbase,
+			wantTx:   1,
+		},
+		{
+			name:     "coinbase without the fees",
+			txs:      []Transaction{coinbase(reward, 1), transfer(alice, bob, 10, 3, 0)},
+			wantCode: TxInvalidCoinbase,
+		},
+		{
+			name:     "coinbase minting more than the reward",
+			txs:      []Transaction{coinbase(reward+1, 1)},
+			wantCode: TxInvalidCoinbase,
+		},
+		{
+			name:     "coinbase nonce other than the block index",
+			txs:      []Transaction{coinbase(reward, 0)},
+			wantCode: TxInvalidCoinbase,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := newLedger()
+			ledger.commit(map[string]Account{alice: {Address: alice, Balance: 100}})
+
+			touched, err := ledger.replay(Block{Index: 1, Transactions: tt.txs}, reward)
+			if tt.wantCode != "" {
+				var txErr *TransactionError
+				if !errors.As(err, &txErr) {
+					t.Fatalf("replay error = %v, want a %s transaction error", err, tt.wantCode)
+				}
+				if txErr.Code != tt.wantCode || txErr.TxIndex != tt.wantTx {
+					t.Fatalf("replay error = %s at tx %d, want %s at tx %d", txErr.Code, txErr.TxIndex, tt.wantCode, tt.wantTx)
+				}
+				if got := ledger.Account(alice); got.Balance != 100 || got.Nonce != 0 {
+					t.Fatalf("rejected block changed the ledger: %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+
+			ledger.commit(touched)
+			for address, want := range tt.want {
+				if got := ledger.Account(address); got != want {
+					t.Errorf("account %s = %+v, want %+v", address, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMinedBlocksPayTheMiner(t *testing.T) {
+	bc := newTestChain(t, WithMiner(miner), WithBlockReward(25))
+	for range 3 {
+		mineTransactions(t, bc, 1)
+	}
+
+	if got := bc.Account(miner); got.Balance != 75 {
+		t.Fatalf("miner balance = %d, want 75", got.Balance)
+	}
+
+	// The stored chain replays to the same balances
+	if report := bc.Validate(); !report.Valid {
+		t.Fatalf("chain failed validation: %+v", report.Errors)
+	}
+	ledger, err := replayLedger(bc.Chain(), 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ledger.Account(miner); got.Balance != 75 {
+		t.Fatalf("replayed miner balance = %d, want 75", got.Balance)
+	}
+}
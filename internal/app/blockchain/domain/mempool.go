@@ -0,0 +1,412 @@
+package domain
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+)
+
+const (
+	DefaultMempoolCapacity      = 1000
+	DefaultMaxBlockTransactions = 100
+)
+
+var (
+	ErrMempoolFull     = errors.New("mempool is full")
+	ErrNothingToMine   = errors.New("no pending transactions ready to mine")
+	ErrAlreadyPending  = errors.New("transaction already pending")
+	ErrMempoolDisabled = errors.New("mempool capacity is zero")
+)
+
+type (
+	// Mempool holds signed transactions waiting to be mined, ordered by fee
+	// (ties in arrival order). When full, a transaction paying more than the
+	// cheapest pending one evicts it. Transfers are admitted only with the
+	// sender's next nonce after the pending ones and when the sender can pay
+	// for all of them, so every pending transfer stays mineable.
+	Mempool struct {
+		entries  txHeap
+		byID     map[string]*mempoolEntry
+		bySender map[string]map[uint64]*mempoolEntry
+		capacity int
+		seq      uint64
+		evicted  uint64
+		ready    chan struct{}
+		mu       sync.Mutex
+	}
+
+	mempoolEntry struct {
+		tx    Transaction
+		seq   uint64
+		index int
+	}
+
+	// txHeap is a max-heap on fee, then a min-heap on arrival
+	txHeap []*mempoolEntry
+
+	MempoolInfo struct {
+		Size         int           `json:"size"`
+		Capacity     int           `json:"capacity"`
+		TotalFees    uint64        `json:"total_fees"`
+		Evicted      uint64        `json:"evicted"`
+		Transactions []Transaction `json:"transactions"`
+	}
+)
+
+func newMempool(capacity int) *Mempool {
+	return &Mempool{
+		byID:     make(map[string]*mempoolEntry),
+		bySender: make(map[string]map[uint64]*mempoolEntry),
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+func (h txHeap) Len() int { return len(h) }
+
+func (h txHeap) Less(i, j int) bool {
+	if h[i].tx.Fee != h[j].tx.Fee {
+		return h[i].tx.Fee > h[j].tx.Fee
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h txHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *txHeap) Push(x any) {
+	entry := x.(*mempoolEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *txHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*h = old[:n-1]
+	return entry
+}
+
+// Ready is signalled after a transaction is added
+func (mp *Mempool) Ready() <-chan struct{} {
+	return mp.ready
+}
+
+func (mp *Mempool) Len() int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return len(mp.entries)
+}
+
+// add admits a verified transaction against the confirmed ledger state and
+// returns the IDs of the transactions it evicted
+func (mp *Mempool) add(tx Transaction, ledger *Ledger) ([]string, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.capacity <= 0 {
+		return nil, ErrMempoolDisabled
+	}
+	if _, ok := mp.byID[tx.ID]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyPending, tx.ID)
+	}
+
+	if tx.IsTransfer() {
+		if txErr := mp.checkSender(tx, ledger); txErr != nil {
+			return nil, txErr
+		}
+	}
+
+	var evicted []string
+	if len(mp.entries) >= mp.capacity {
+		cheapest := mp.cheapest()
+		if cheapest.tx.Fee >= tx.Fee {
+			return nil, fmt.Errorf("%w: fee %d does not beat the lowest pending fee %d", ErrMempoolFull, tx.Fee, cheapest.tx.Fee)
+		}
+		if tx.IsTransfer() && cheapest.tx.From == tx.From {
+			// Evicting a predecessor would leave a nonce gap
+			return nil, fmt.Errorf("%w: the lowest fee is an earlier transfer of the same sender", ErrMempoolFull)
+		}
+		evicted = mp.evict(cheapest)
+	}
+
+	mp.seq++
+	entry := &mempoolEntry{tx: tx, seq: mp.seq}
+	heap.Push(&mp.entries, entry)
+	mp.byID[tx.ID] = entry
+	if tx.IsTransfer() {
+		if mp.bySender[tx.From] == nil {
+			mp.bySender[tx.From] = make(map[uint64]*mempoolEntry)
+		}
+		mp.bySender[tx.From][tx.Nonce] = entry
+	}
+
+	select {
+	case mp.ready <- struct{}{}:
+	default:
+	}
+
+	return evicted, nil
+}
+
+// checkSender requires the next free nonce of the sender and enough balance
+// to pay for every pending transfer. Callers must hold mp.mu.
+func (mp *Mempool) checkSender(tx Transaction, ledger *Ledger) *TransactionError {
+	account := ledger.Account(tx.From)
+	pending := mp.bySender[tx.From]
+
+	next := account.Nonce + uint64(len(pending))
+	switch {
+	case tx.Nonce < account.Nonce:
+		return &TransactionError{TxID: tx.ID, Code: TxInvalidNonce, Reason: fmt.Sprintf("nonce %d already mined, expected %d", tx.Nonce, next)}
+	case pending[tx.Nonce] != nil:
+		return &TransactionError{TxID: tx.ID, Code: TxInvalidNonce, Reason: fmt.Sprintf("nonce %d already pending in %s, expected %d", tx.Nonce, pending[tx.Nonce].tx.ID, next)}
+	case tx.Nonce != next:
+		return &TransactionError{TxID: tx.ID, Code: TxInvalidNonce, Reason: fmt.Sprintf("nonce %d, expected %d", tx.Nonce, next)}
+	}
+
+	view := ledger.view()
+	for nonce := account.Nonce; nonce < next; nonce++ {
+		// Pending transfers were checked on admission; replaying them leaves
+		// the balance the new one can spend
+		_ = view.transfer(0, pending[nonce].tx)
+	}
+	return view.transfer(0, tx)
+}
+
+// cheapest returns the entry evicted first: the lowest fee, latest arrival.
+// Callers must hold mp.mu.
+func (mp *Mempool) cheapest() *mempoolEntry {
+	// The max-heap keeps its minimum among the leaves
+	var cheapest *mempoolEntry
+	for _, entry := range mp.entries[len(mp.entries)/2:] {
+		if cheapest == nil || mp.entries.Less(cheapest.index, entry.index) {
+			cheapest = entry
+		}
+	}
+	return cheapest
+}
+
+// evict removes entry and the later transfers of its sender, which could no
+// longer be mined. Callers must hold mp.mu.
+func (mp *Mempool) evict(entry *mempoolEntry) []string {
+	var evicted []string
+	if entry.tx.IsTransfer() {
+		for nonce, dependent := range mp.bySender[entry.tx.From] {
+			if nonce > entry.tx.Nonce {
+				mp.remove(dependent)
+				evicted = append(evicted, dependent.tx.ID)
+			}
+		}
+	}
+	mp.remove(entry)
+	mp.evicted += uint64(len(evicted) + 1)
+	return append(evicted, entry.tx.ID)
+}
+
+// remove drops entry. Callers must hold mp.mu.
+func (mp *Mempool) remove(entry *mempoolEntry) {
+	heap.Remove(&mp.entries, entry.index)
+	delete(mp.byID, entry.tx.ID)
+	if entry.tx.IsTransfer() {
+		delete(mp.bySender[entry.tx.From], entry.tx.Nonce)
+		if len(mp.bySender[entry.tx.From]) == 0 {
+			delete(mp.bySender, entry.tx.From)
+		}
+	}
+}
+
+// selectFor picks up to limit pending transactions that apply to ledger, in
+// fee order. A transfer whose nonce comes after a pending one of the same
+// sender is taken in a later pass, once its predecessor has been selected.
+func (mp *Mempool) selectFor(ledger *Ledger, limit int) []Transaction {
+	mp.mu.Lock()
+	ordered := mp.ordered()
+	mp.mu.Unlock()
+
+	view := ledger.view()
+	selected := make([]Transaction, 0, min(limit, len(ordered)))
+
+	for progress := true; progress && len(selected) < limit; {
+		progress = false
+		for i, entry := range ordered {
+			if entry == nil || len(selected) == limit {
+				continue
+			}
+			if entry.tx.IsTransfer() {
+				if view.transfer(len(selected), entry.tx) != nil {
+					continue
+				}
+			}
+			selected = append(selected, entry.tx)
+			ordered[i] = nil
+			progress = true
+		}
+	}
+
+	return selected
+}
+
+// ordered returns the entries in priority order. Callers must hold mp.mu.
+func (mp *Mempool) ordered() []*mempoolEntry {
+	ordered := make([]*mempoolEntry, len(mp.entries))
+	copy(ordered, mp.entries)
+	sort.Slice(ordered, func(i, j int) bool {
+		return mp.entries.Less(ordered[i].index, ordered[j].index)
+	})
+	return ordered
+}
+
+// removeMined drops the transactions included in block and the transfers
+// whose nonce the ledger has moved past
+func (mp *Mempool) removeMined(block Block, ledger *Ledger) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for _, tx := range block.Transactions {
+		if entry, ok := mp.byID[tx.ID]; ok {
+			mp.remove(entry)
+		}
+		if tx.IsTransfer() && tx.Signed() {
+			account := ledger.Account(tx.From)
+			for nonce, stale := range mp.bySender[tx.From] {
+				if nonce < account.Nonce {
+					mp.remove(stale)
+				}
+			}
+		}
+	}
+}
+
+// readmit empties the pool and admits restored, then its own transactions
+// in arrival order, against ledger, skipping the ones blocks include.
+// restored are the transactions of blocks that left the canonical chain,
+// which came before anything still pending. It is used when the canonical
+// chain changes under the pool and returns the number of pending
+// transactions that did not make it back.
+func (mp *Mempool) readmit(restored []Transaction, blocks []Block, ledger *Ledger) int {
+	mp.mu.Lock()
+	entries := slices.Clone(mp.entries)
+	mp.entries = nil
+	clear(mp.byID)
+	clear(mp.bySender)
+	mp.mu.Unlock()
+
+	if len(entries) == 0 && len(restored) == 0 {
+		return 0
+	}
+
+	included := make(map[string]struct{})
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			included[tx.ID] = struct{}{}
+		}
+	}
+
+	for _, tx := range restored {
+		if _, ok := included[tx.ID]; !ok {
+			_, _ = mp.add(tx, ledger)
+		}
+	}
+
+	// Arrival order keeps the nonces of every sender in sequence
+	slices.SortFunc(entries, func(a, b *mempoolEntry) int { return cmp.Compare(a.seq, b.seq) })
+
+	dropped := 0
+	for _, entry := range entries {
+		if _, ok := included[entry.tx.ID]; ok {
+			dropped++
+			continue
+		}
+		if _, err := mp.add(entry.tx, ledger); err != nil {
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// pending returns the number of transfers of address waiting in the pool
+func (mp *Mempool) pending(address string) int {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	return len(mp.bySender[address])
+}
+
+// Info returns the pending transactions in priority order
+func (mp *Mempool) Info() MempoolInfo {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	info := MempoolInfo{
+		Size:         len(mp.entries),
+		Capacity:     mp.capacity,
+		Evicted:      mp.evicted,
+		Transactions: make([]Transaction, 0, len(mp.entries)),
+	}
+	for _, entry := range mp.ordered() {
+		info.TotalFees += entry.tx.Fee
+		info.Transactions = append(info.Transactions, entry.tx)
+	}
+	return info
+}
+
+// SubmitTransaction verifies a signed transaction and adds it to the mempool.
+// It returns the IDs of the pending transactions evicted to make room.
+func (bc *Blockchain) SubmitTransaction(tx Transaction) ([]string, error) {
+	if err := VerifySignedTransaction(tx); err != nil {
+		return nil, err
+	}
+
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.mempool.add(tx, bc.ledger)
+}
+
+// Mempool returns the pending transactions
+func (bc *Blockchain) Mempool() MempoolInfo {
+	return bc.mempool.Info()
+}
+
+// NextNonce returns the nonce the next transfer of address must carry,
+// counting the transfers already pending
+func (bc *Blockchain) NextNonce(address string) uint64 {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+	return bc.ledger.Account(address).Nonce + uint64(bc.mempool.pending(address))
+}
+
+// MinePending consumes the mempool until ctx is done: every time
+// transactions are submitted it mines blocks from the pool until nothing
+// mineable is left
+func (bc *Blockchain) MinePending(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-bc.mempool.Ready():
+		}
+
+		for ctx.Err() == nil {
+			block, _, err := bc.AddBlock(ctx, Payload{FromMempool: true})
+			if err != nil {
+				if !errors.Is(err, ErrNothingToMine) && ctx.Err() == nil {
+					log.Printf("mining pending transactions: %v", err)
+				}
+				break
+			}
+			log.Printf("Mined block %d with %d transactions from the mempool", block.Index, len(block.Transactions))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMempoolEviction(t *testing.T) {
+	transfer := func(id, from string, fee, nonce uint64) Transaction {
+		return Transaction{ID: id, From: from, To: bob, Amount: 1, Fee: fee, Nonce: nonce}
+	}
+
+	tests := []struct {
+		name        string
+		capacity    int
+		pending     []Transaction
+		incoming    Transaction
+		wantErr     error
+		wantEvicted []string
+		// wantOrder lists the pending IDs by priority after the incoming transaction
+		wantOrder []string
+	}{
+		{
+			name:     "room left",
+			capacity: 3,
+			pending:  []Transaction{transfer("a", "s1", 1, 0)},
+			incoming: transfer("b", "s2", 1, 0),
+			// Equal fees keep arrival order
+			wantOrder: []string{"a", "b"},
+		},
+		{
+			name:        "higher fee evicts the cheapest",
+			capacity:    3,
+			pending:     []Transaction{transfer("a", "s1", 5, 0), transfer("b", "s2", 1, 0), transfer("c", "s3", 3, 0)},
+			incoming:    transfer("d", "s4", 2, 0),
+			wantEvicted: []string{"b"},
+			wantOrder:   []string{"a", "c", "d"},
+		},
+		{
+			name:        "equal fees evict the latest arrival",
+			capacity:    3,
+			pending:     []Transaction{transfer("a", "s1", 1, 0), transfer("b", "s2", 1, 0), transfer("c", "s3", 5, 0)},
+			incoming:    transfer("d", "s4", 2, 0),
+			wantEvicted: []string{"b"},
+			wantOrder:   []string{"c", "d", "a"},
+		},
+		{
+			name:      "fee not above the cheapest is refused",
+			capacity:  2,
+			pending:   []Transaction{transfer("a", "s1", 2, 0), transfer("b", "s2", 3, 0)},
+			incoming:  transfer("c", "s3", 2, 0),
+			wantErr:   ErrMempoolFull,
+			wantOrder: []string{"b", "a"},
+		},
+		{
+			name:        "eviction drops the later transfers of the sender",
+			capacity:    3,
+			pending:     []Transaction{transfer("a", "s1", 5, 0), transfer("b", "s2", 1, 0), transfer("c", "s2", 4, 1)},
+			incoming:    transfer("d", "s3", 2, 0),
+			wantEvicted: []string{"c", "b"},
+			wantOrder:   []string{"a", "d"},
+		},
+		{
+			name:      "the sender's own predecessor is not evicted",
+			capacity:  2,
+			pending:   []Transaction{transfer("a", "s1", 1, 0), transfer("b", "s2", 5, 0)},
+			incoming:  transfer("c", "s1", 3, 1),
+			wantErr:   ErrMempoolFull,
+			wantOrder: []string{"b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := fundedLedger("s1", "s2", "s3", "s4")
+			mp := newMempool(tt.capacity)
+			for _, tx := range tt.pending {
+				if _, err := mp.add(tx, ledger); err != nil {
+					t.Fatalf("add(%s): %v", tx.ID, err)
+				}
+			}
+
+			evicted, err := mp.add(tt.incoming, ledger)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("add error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(evicted, tt.wantEvicted) {
+				t.Fatalf("evicted = %v, want %v", evicted, tt.wantEvicted)
+			}
+			if order := pendingIDs(mp); !slices.Equal(order, tt.wantOrder) {
+				t.Fatalf("pending = %v, want %v", order, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestMempoolSelectsByFeeInNonceOrder(t *testing.T) {
+	ledger := fundedLedger("s1", "s2")
+	mp := newMempool(10)
+	for _, tx := range []Transaction{
+		{ID: "a", From: "s1", To: bob, Amount: 1, Fee: 1, Nonce: 0},
+		// Pays the most but has to wait for a
+		{ID: "b", From: "s1", To: bob, Amount: 1, Fee: 9, Nonce: 1},
+		{ID: "c", From: "s2", To: bob, Amount: 1, Fee: 5, Nonce: 0},
+	} {
+		if _, err := mp.add(tx, ledger); err != nil {
+			t.Fatalf("add(%s): %v", tx.ID, err)
+		}
+	}
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{limit: 10, want: []string{"c", "a", "b"}},
+		{limit: 2, want: []string{"c", "a"}},
+		{limit: 1, want: []string{"c"}},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, tx := range mp.selectFor(ledger, tt.limit) {
+			ids = append(ids, tx.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("selectFor(%d) = %v, want %v", tt.limit, ids, tt.want)
+		}
+	}
+}
+
+// fundedLedger credits 100 to every address
+func fundedLedger(addresses ...string) *Ledger {
+	ledger := newLedger()
+	for _, address := range addresses {
+		ledger.commit(map[string]Account{address: {Address: address, Balance: 100}})
+	}
+	return ledger
+}
+
+func pendingIDs(mp *Mempool) []string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var ids []string
+	for _, entry := range mp.ordered() {
+		ids = append(ids, entry.tx.ID)
+	}
+	return ids
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	// SiblingLeft means the sibling is hashed before the running node
+	SiblingLeft = "left"
+	// SiblingRight means the sibling is hashed after the running node
+	SiblingRight = "right"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidProof        = errors.New("invalid merkle proof")
+)
+
+type (
+	// MerkleProof is the path from a transaction ID up to a block Merkle root
+	MerkleProof struct {
+		TxID       string            `json:"tx_id"`
+		TxIndex    int               `json:"tx_index"`
+		MerkleRoot string            `json:"merkle_root"`
+		Path       []MerkleProofStep `json:"path"`
+	}
+
+	MerkleProofStep struct {
+		Hash string `json:"hash"`
+		Side string `json:"side"`
+	}
+)
+
+// MerkleRoot builds a binary SHA-256 tree over the transaction IDs, hashing
+// each pair as sha256(left || right) and duplicating the last node of odd
+// levels. A block without transactions has ZeroHash as its root.
+func MerkleRoot(txs []Transaction) (Hash, error) {
+	leaves, err := merkleLeaves(txs)
+	if err != nil {
+		return Hash{}, err
+	}
+
+	if len(leaves) == 0 {
+		return ZeroHash, nil
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		level = nextMerkleLevel(level)
+	}
+
+	return level[0], nil
+}
+
+func merkleLeaves(txs []Transaction) ([][HashSize]byte, error) {
+	leaves := make([][HashSize]byte, len(txs))
+	for i, tx := range txs {
+		leaf, err := decodeHash(tx.ID)
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = leaf
+	}
+	return leaves, nil
+}
+
+func nextMerkleLevel(level [][HashSize]byte) [][HashSize]byte {
+	next := make([][HashSize]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		right := level[i]
+		if i+1 < len(level) {
+			right = level[i+1]
+		}
+		next = append(next, hashMerklePair(level[i], right))
+	}
+	return next
+}
+
+func hashMerklePair(left, right [HashSize]byte) [HashSize]byte {
+	var buf [2 * HashSize]byte
+	copy(buf[:HashSize], left[:])
+	copy(buf[HashSize:], right[:])
+	return sha256.Sum256(buf[:])
+}
+
+// BuildMerkleProof returns the sibling hashes needed to recompute the Merkle
+// root of txs starting from the transaction with the given ID
+func BuildMerkleProof(txs []Transaction, txID string) (MerkleProof, error) {
+	leaves, err := merkleLeaves(txs)
+	if err != nil {
+		return MerkleProof{}, err
+	}
+
+	position := -1
+	for i, tx := range txs {
+		if tx.ID == txID {
+			position = i
+			break
+		}
+	}
+	if position < 0 {
+		return MerkleProof{}, fmt.Errorf("%w: %s", ErrTransactionNotFound, txID)
+	}
+
+	proof := MerkleProof{
+		TxID:    txID,
+		TxIndex: position,
+		Path:    make([]MerkleProofStep, 0),
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		sibling := position ^ 1
+		side := SiblingRight
+		if position%2 == 1 {
+			side = SiblingLeft
+		}
+		if sibling >= len(level) {
+			sibling = position
+		}
+
+		proof.Path = append(proof.Path, MerkleProofStep{
+			Hash: hex.EncodeToString(level[sibling][:]),
+			Side: side,
+		})
+
+		level = nextMerkleLevel(level)
+		position /= 2
+	}
+
+	proof.MerkleRoot = hex.EncodeToString(level[0][:])
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that proof links its transaction to the Merkle root
+// of header without needing the block transactions. header must be
+// self-consistent: its hash is recomputed with hasher from the header fields alone.
+func VerifyMerkleProof(header Block, proof MerkleProof, hasher Hasher) error {
+	if header.Version < EncodingV3 {
+		return fmt.Errorf("%w: block %d has no merkle root (encoding version %d)", ErrInvalidProof, header.Index, header.Version)
+	}
+
+	hash, err := calculateHash(header, hasher)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+	if hash != header.Hash {
+		return fmt.Errorf("%w: header hash %q does not match its fields (computed %q)", ErrInvalidProof, header.Hash, hash)
+	}
+
+	node, err := decodeHash(proof.TxID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidProof, err)
+	}
+
+	for i, step := range proof.Path {
+		sibling, err := decodeHash(step.Hash)
+		if err != nil {
+			return fmt.Errorf("%w: step %d: %v", ErrInvalidProof, i, err)
+		}
+
+		switch step.Side {
+		case SiblingLeft:
+			node = hashMerklePair(sibling, node)
+		case SiblingRight:
+			node = hashMerklePair(node, sibling)
+		default:
+			return fmt.Errorf("%w: step %d: unknown side %q", ErrInvalidProof, i, step.Side)
+		}
+	}
+
+	if root := Hash(node); !header.MerkleRoot.Valid || root != header.MerkleRoot.Hash {
+		return fmt.Errorf("%w: proof yields root %q, header has %q", ErrInvalidProof, root, header.MerkleRoot)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMerkleProofsVerifyEveryTransaction(t *testing.T) {
+	// Odd counts duplicate the last node of a level, 1 has an empty path
+	for _, count := range []int{1, 2, 3, 5, 8} {
+		t.Run(fmt.Sprintf("%d transactions", count), func(t *testing.T) {
+			bc := newTestChain(t)
+			block := mineTransactions(t, bc, count)
+
+			for _, tx := range block.Transactions {
+				header, proof, err := bc.TransactionProof(block.Index, tx.ID)
+				if err != nil {
+					t.Fatalf("TransactionProof(%s): %v", tx.ID, err)
+				}
+				if err := VerifyMerkleProof(header, proof, bc.Params().Hasher); err != nil {
+					t.Fatalf("VerifyMerkleProof(%s): %v", tx.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleProofRejectsTampering(t *testing.T) {
+	bc := newTestChain(t)
+	block := mineTransactions(t, bc, 5)
+	other := block.Transactions[1].ID
+
+	tests := []struct {
+		name   string
+		tamper func(header *Block, proof *MerkleProof)
+	}{
+		{
+			name:   "other transaction",
+			tamper: func(_ *Block, proof *MerkleProof) { proof.TxID = other },
+		},
+		{
+			name:   "swapped side",
+			tamper: func(_ *Block, proof *MerkleProof) { proof.Path[0].Side = SiblingLeft },
+		},
+		{
+			name:   "unknown side",
+			tamper: func(_ *Block, proof *MerkleProof) { proof.Path[0].Side = "up" },
+		},
+		{
+			name:   "changed sibling",
+			tamper: func(_ *Block, proof *MerkleProof) { proof.Path[1].Hash = other },
+		},
+		{
+			name:   "truncated path",
+			tamper: func(_ *Block, proof *MerkleProof) { proof.Path = proof.Path[:1] },
+		},
+		{
+			name: "merkle root changed in the header",
+			tamper: func(header *Block, _ *MerkleProof) {
+				header.MerkleRoot = SomeHash(mustParseHash(t, other))
+			},
+		},
+		{
+			name: "header without merkle root",
+			tamper: func(header *Block, _ *MerkleProof) {
+				header.Version = EncodingV2
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, proof, err := bc.TransactionProof(block.Index, block.Transactions[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(&header, &proof)
+			if err := VerifyMerkleProof(header, proof, bc.Params().Hasher); !errors.Is(err, ErrInvalidProof) {
+				t.Fatalf("VerifyMerkleProof error = %v, want %v", err, ErrInvalidProof)
+			}
+		})
+	}
+}
+
+func TestTransactionProofUnknownTransaction(t *testing.T) {
+	bc := newTestChain(t)
+	block := mineTransactions(t, bc, 2)
+
+	_, _, err := bc.TransactionProof(block.Index, ZeroHash.String())
+	if !errors.Is(err, ErrTransactionNotFound) {
+		t.Fatalf("TransactionProof error = %v, want %v", err, ErrTransactionNotFound)
+	}
+}
+
+// newTestChain returns an in-memory chain at the lowest hex difficulty, so
+// mining a block takes a few dozen hashes
+func newTestChain(t *testing.T, opts ...Option) *Blockchain {
+	t.Helper()
+	bc, err := NewBlockchain(1, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = bc.Close() })
+	return bc
+}
+
+// mineTransactions mines a block on the tip of bc holding count data transactions
+func mineTransactions(t *testing.T, bc *Blockchain, count int) Block {
+	t.Helper()
+	var payload Payload
+	for i := range count {
+		payload.Transactions = append(payload.Transactions, NewTransaction(fmt.Sprintf("tx %d", i), time.Now()))
+	}
+	block, _, err := bc.AddBlock(context.Background(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return block
+}
@@ -0,0 +1,428 @@
+package domain
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// cancelCheckInterval is how many nonces are tried between context checks
+	cancelCheckInterval = 1024
+
+	// nonceOffset is the position of the nonce in an encoded header, right
+	// after the version byte and the index
+	nonceOffset = 1 + 8
+)
+
+const (
+	// HashImplOptimized encodes the header once per search, rewrites the
+	// nonce bytes in place, checks the raw digest and hex-encodes only the
+	// winning hash: nothing is allocated per nonce
+	HashImplOptimized HashImpl = "optimized"
+	// HashImplNaive is how blocks were mined before the optimized path: the
+	// header is encoded into a new buffer and the digest hex-encoded for every
+	// nonce, and the target is checked on the hex string. Every nonce
+	// allocates, and all of it but the winner is garbage.
+	HashImplNaive HashImpl = "naive"
+)
+
+var ErrInvalidHashImpl = errors.New("invalid hash implementation")
+
+type (
+	// HashImpl selects how the nonce search hashes candidate headers
+	HashImpl string
+
+	// MiningOptions tune how a block is mined without changing the block
+	MiningOptions struct {
+		// HashImpl defaults to HashImplOptimized
+		HashImpl HashImpl
+		// Yield defaults to YieldGosched, YieldInterval to DefaultYieldInterval
+		Yield         YieldStrategy
+		YieldInterval int
+	}
+
+	// nonceSearch holds what every nonce of a block is hashed and checked with
+	nonceSearch struct {
+		zeroBits int
+		// hexTarget is the run of zero characters the naive search compares
+		// hex-encoded hashes with
+		hexTarget string
+		refs      headerRefs
+		hasher    Hasher
+		impl      HashImpl
+		yield     YieldStrategy
+		// yieldInterval is 0 when the search never yields
+		yieldInterval int
+	}
+
+	// MiningStats is the telemetry of mining one block: what the nonce search
+	// cost and how long the goroutine waited for the chain lock before it
+	MiningStats struct {
+		Index  int `json:"index"`
+		Worker int `json:"worker"`
+		Hashes int `json:"hashes"`
+		// DurationMs is the time spent searching nonces, excluding the lock wait
+		DurationMs float64 `json:"duration_ms"`
+		HashRate   float64 `json:"hash_rate"`
+		// Yields counts the yields of the search, whatever the strategy
+		Yields     int     `json:"yields"`
+		LockWaitMs float64 `json:"lock_wait_ms"`
+	}
+
+	// WorkerStats describes the share of the nonce space searched by one worker
+	WorkerStats struct {
+		MiningStats
+		Winner bool `json:"winner"`
+	}
+
+	// CommitStats describes how a block mined outside the chain lock was
+	// committed. The mining stats add up every attempt, wasted ones included.
+	CommitStats struct {
+		MiningStats
+		Retries      int     `json:"commit_retries"`
+		WastedHashes int     `json:"wasted_hashes"`
+		LockHoldMs   float64 `json:"lock_hold_ms"`
+	}
+)
+
+func ParseHashImpl(value string) (HashImpl, error) {
+	switch impl := HashImpl(value); impl {
+	case HashImplOptimized, HashImplNaive:
+		return impl, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidHashImpl, value)
+	}
+}
+
+// MineSplitNonce mines a single block on top of the tip with numWorkers
+// goroutines searching interleaved slices of the nonce space (worker i tries
+// i, i+numWorkers, i+2*numWorkers, ...). The first worker to find a valid
+// hash wins and the others are cancelled. The block stats add up the
+// workers, with the wall time of the whole search as duration.
+func (bc *Blockchain) MineSplitNonce(ctx context.Context, payload Payload, numWorkers int) (Block, MiningStats, []WorkerStats, error) {
+	if err := payload.verify(); err != nil {
+		return Block{}, MiningStats{}, nil, err
+	}
+
+	lockWait := bc.lockTimed()
+	defer bc.mu.Unlock()
+
+	candidate, err := bc.nextBlock(payload)
+	if err != nil {
+		return Block{}, MiningStats{}, nil, err
+	}
+	search, err := bc.newNonceSearch(candidate, payload.Mining)
+	if err != nil {
+		return Block{}, MiningStats{}, nil, err
+	}
+
+	searchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		winOnce sync.Once
+		winner  Block
+		found   bool
+	)
+	stats := make([]WorkerStats, numWorkers)
+	start := time.Now()
+
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+
+			block := candidate
+			block.Nonce = id
+			mined, err := search.run(searchCtx, &block, numWorkers, bc.progressReporter(&block, id))
+			mined.Worker = id
+			stats[id] = WorkerStats{MiningStats: mined}
+			if err != nil {
+				return
+			}
+
+			winOnce.Do(func() {
+				winner, found = block, true
+				stats[id].Winner = true
+				cancel()
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	total := MiningStats{Index: candidate.Index, LockWaitMs: milliseconds(lockWait)}
+	for _, s := range stats {
+		total.Hashes += s.Hashes
+		total.Yields += s.Yields
+		if s.Winner {
+			total.Worker = s.Worker
+		}
+	}
+	total.DurationMs = milliseconds(time.Since(start))
+	total.HashRate = HashRate(total.Hashes, total.DurationMs)
+
+	if !found {
+		return Block{}, total, stats, &MiningAbortedError{Index: candidate.Index, NoncesTried: total.Hashes, Err: context.Cause(ctx)}
+	}
+
+	if err := bc.appendBlock(winner); err != nil {
+		return Block{}, total, stats, err
+	}
+
+	return winner, total, stats, nil
+}
+
+func (bc *Blockchain) mineBlock(ctx context.Context, block *Block, options MiningOptions) (MiningStats, error) {
+	search, err := bc.newNonceSearch(*block, options)
+	if err != nil {
+		return MiningStats{Index: block.Index}, err
+	}
+
+	return search.run(ctx, block, 1, bc.progressReporter(block, 0))
+}
+
+// lockTimed acquires the chain write lock and returns how long it waited
+func (bc *Blockchain) lockTimed() time.Duration {
+	start := time.Now()
+	bc.mu.Lock()
+	return time.Since(start)
+}
+
+func (bc *Blockchain) newNonceSearch(block Block, options MiningOptions) (nonceSearch, error) {
+	refs, err := decodeHeaderRefs(block)
+	if err != nil {
+		return nonceSearch{}, err
+	}
+
+	options = options.WithDefaults()
+	zeroBits := bc.params.Mode.ZeroBits(block.Difficulty)
+
+	return nonceSearch{
+		zeroBits:      zeroBits,
+		hexTarget:     strings.Repeat("0", zeroBits/4),
+		refs:          refs,
+		hasher:        bc.params.Hasher,
+		impl:          options.HashImpl,
+		yield:         options.Yield,
+		yieldInterval: options.YieldInterval,
+	}, nil
+}
+
+// WithDefaults fills in the options left empty, as the nonce search uses them
+func (o MiningOptions) WithDefaults() MiningOptions {
+	if o.HashImpl == "" {
+		o.HashImpl = HashImplOptimized
+	}
+	if o.Yield == "" {
+		o.Yield = YieldGosched
+	}
+	if o.YieldInterval == 0 {
+		o.YieldInterval = DefaultYieldInterval
+	}
+	if o.Yield == YieldNone {
+		o.YieldInterval = 0
+	}
+	return o
+}
+
+// run tries block.Nonce, block.Nonce+stride, ... until the digest has
+// zeroBits leading zero bits or ctx is done, returning the hashes computed,
+// the time taken and the yields. progress is called with the number of hashes
+// along with the context checks.
+func (s nonceSearch) run(ctx context.Context, block *Block, stride int, progress func(hashes int)) (MiningStats, error) {
+	stats := MiningStats{Index: block.Index}
+	if ctx.Err() != nil {
+		return stats, &MiningAbortedError{Index: block.Index, Err: context.Cause(ctx)}
+	}
+	done := ctx.Done()
+
+	if s.yield == YieldLockOSThread {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+	}
+
+	start := time.Now()
+	finish := func(hashes int) MiningStats {
+		stats.Hashes = hashes
+		stats.DurationMs = milliseconds(time.Since(start))
+		stats.HashRate = HashRate(hashes, stats.DurationMs)
+		return stats
+	}
+
+	// The optimized path hashes buf, whose nonce bytes are rewritten for every try
+	var buf []byte
+	if s.impl == HashImplOptimized {
+		buf = appendHeader(make([]byte, 0, headerFixedSize+len(block.Data)), *block, s.refs)
+	}
+
+	for hashes := 1; ; hashes++ {
+		if s.impl == HashImplOptimized {
+			binary.BigEndian.PutUint64(buf[nonceOffset:], uint64(block.Nonce))
+			digest := s.hasher.Sum(buf)
+			if leadingZeroBits(digest[:]) >= s.zeroBits {
+				block.Hash = digest
+				return finish(hashes), nil
+			}
+		} else {
+			digest := sumHeader(*block, s.refs, s.hasher)
+			if hexMeetsTarget(hex.EncodeToString(digest[:]), s.hexTarget, s.zeroBits) {
+				block.Hash = digest
+				return finish(hashes), nil
+			}
+		}
+
+		block.Nonce += stride
+
+		if hashes%cancelCheckInterval == 0 {
+			select {
+			case <-done:
+				return finish(hashes), &MiningAbortedError{Index: block.Index, NoncesTried: hashes, Err: context.Cause(ctx)}
+			default:
+			}
+			progress(hashes)
+		}
+
+		// Give other goroutines a chance to run without waiting for preemption
+		if s.yieldInterval > 0 && hashes%s.yieldInterval == 0 {
+			s.yield.yield()
+			stats.Yields++
+		}
+	}
+}
+
+// MineOptimistic mines a candidate block without holding the chain lock and
+// commits it only if the tip it was built on is still the tip, re-mining on
+// top of the new tip otherwise
+func (bc *Blockchain) MineOptimistic(ctx context.Context, payload Payload) (Block, CommitStats, error) {
+	var stats CommitStats
+
+	if err := payload.verify(); err != nil {
+		return Block{}, stats, err
+	}
+
+	for {
+		waitStart := time.Now()
+		bc.mu.RLock()
+		stats.LockWaitMs += milliseconds(time.Since(waitStart))
+		candidate, err := bc.nextBlock(payload)
+		bc.mu.RUnlock()
+		if err != nil {
+			return Block{}, stats, err
+		}
+
+		search, err := bc.newNonceSearch(candidate, payload.Mining)
+		if err != nil {
+			return Block{}, stats, err
+		}
+
+		attempt, err := search.run(ctx, &candidate, 1, bc.progressReporter(&candidate, 0))
+		stats.add(attempt)
+		if err != nil {
+			return Block{}, stats, err
+		}
+
+		committed, waitTime, holdTime, err := bc.compareAndAppend(candidate)
+		stats.LockWaitMs += milliseconds(waitTime)
+		stats.LockHoldMs += milliseconds(holdTime)
+		if err != nil {
+			return Block{}, stats, err
+		}
+		if committed {
+			return candidate, stats, nil
+		}
+
+		stats.Retries++
+		stats.WastedHashes += attempt.Hashes
+	}
+}
+
+// MineParallelOptimistic runs MineOptimistic in numGoroutines goroutines, each
+// committing its own block. Mining happens outside the lock, so goroutines
+// only contend for the short compare-and-append.
+func (bc *Blockchain) MineParallelOptimistic(ctx context.Context, payload Payload, numGoroutines int) ([]Block, []CommitStats, time.Duration, error) {
+	start := time.Now()
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		mineErr error
+		mu      sync.Mutex
+	)
+	blocks := make([]Block, 0, numGoroutines)
+	commits := make([]CommitStats, 0, numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+
+			block, stats, err := bc.MineOptimistic(ctx, payload.forWorker(id))
+			if errors.Is(err, ErrNothingToMine) {
+				return
+			}
+			if err != nil {
+				errOnce.Do(func() { mineErr = err })
+				return
+			}
+			stats.Worker = id
+
+			mu.Lock()
+			blocks = append(blocks, block)
+			commits = append(commits, stats)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	if len(blocks) == 0 && mineErr == nil {
+		mineErr = ErrNothingToMine
+	}
+
+	return blocks, commits, time.Since(start), mineErr
+}
+
+// compareAndAppend appends block only if its previous hash is still the tip,
+// returning how long it waited for the write lock and how long it held it
+func (bc *Blockchain) compareAndAppend(block Block) (committed bool, wait, hold time.Duration, err error) {
+	wait = bc.lockTimed()
+	acquired := time.Now()
+	defer bc.mu.Unlock()
+
+	if bc.chain[len(bc.chain)-1].Hash != block.PreviousHash {
+		return false, wait, time.Since(acquired), nil
+	}
+
+	err = bc.appendBlock(block)
+	return err == nil, wait, time.Since(acquired), err
+}
+
+// add accumulates another search made by the same goroutine for the same block
+func (s *MiningStats) add(other MiningStats) {
+	s.Index = other.Index
+	s.Hashes += other.Hashes
+	s.DurationMs += other.DurationMs
+	s.Yields += other.Yields
+	s.LockWaitMs += other.LockWaitMs
+	s.HashRate = HashRate(s.Hashes, s.DurationMs)
+}
+
+// HashRate returns the hashes per second, or 0 when no time was measured so
+// that the rate always encodes to JSON
+func HashRate(hashes int, durationMs float64) float64 {
+	if durationMs <= 0 {
+		return 0
+	}
+	return float64(hashes) / durationMs * 1000
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
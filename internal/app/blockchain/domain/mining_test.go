@@ -0,0 +1,274 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMineSplitNonceWinner(t *testing.T) {
+	for _, workers := range []int{1, 2, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			bc := newTestChain(t)
+
+			block, stats, workerStats, err := bc.MineSplitNonce(context.Background(), Payload{Data: "split"}, workers)
+			if err != nil {
+				t.Fatalf("MineSplitNonce: %v", err)
+			}
+			if bc.Length() != 2 || bc.Tip().Hash != block.Hash {
+				t.Fatalf("chain has %d blocks with tip %s, want the mined block %s on top", bc.Length(), bc.Tip().Hash, block.Hash)
+			}
+			if report := bc.Validate(); !report.Valid {
+				t.Fatalf("chain failed validation: %+v", report.Errors)
+			}
+
+			if len(workerStats) != workers {
+				t.Fatalf("got stats for %d workers, want %d", len(workerStats), workers)
+			}
+			winners, hashes := 0, 0
+			for id, s := range workerStats {
+				if s.Worker != id {
+					t.Errorf("stats %d report worker %d", id, s.Worker)
+				}
+				if s.Winner {
+					winners++
+					// The winner searched the nonces congruent to its ID
+					if block.Nonce%workers != id || stats.Worker != id {
+						t.Errorf("worker %d won with nonce %d, block stats name worker %d", id, block.Nonce, stats.Worker)
+					}
+				}
+				hashes += s.Hashes
+			}
+			if winners != 1 {
+				t.Fatalf("%d winners, want 1", winners)
+			}
+			if stats.Hashes != hashes || stats.Index != block.Index {
+				t.Fatalf("block stats = %+v, want %d hashes for block %d", stats, hashes, block.Index)
+			}
+		})
+	}
+}
+
+func TestMineSplitNonceAborts(t *testing.T) {
+	tests := []struct {
+		name    string
+		context func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{
+			name: "cancelled before the search",
+			context: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "cancelled during the search",
+			context: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(20*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			wantErr: context.Canceled,
+		},
+		{
+			name: "deadline expired",
+			context: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No digest has every bit zero, so only the context ends the search
+			bc, err := NewBlockchain(DifficultyHex.MaxDifficulty())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = bc.Close() })
+
+			ctx, cancel := tt.context()
+			defer cancel()
+
+			_, stats, workerStats, err := bc.MineSplitNonce(ctx, Payload{Data: "never"}, 4)
+			var aborted *MiningAbortedError
+			if !errors.As(err, &aborted) || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MineSplitNonce error = %v, want an abort caused by %v", err, tt.wantErr)
+			}
+			if aborted.NoncesTried != stats.Hashes {
+				t.Fatalf("error reports %d nonces, stats %d", aborted.NoncesTried, stats.Hashes)
+			}
+			for _, s := range workerStats {
+				if s.Winner {
+					t.Fatalf("worker %d won an aborted search", s.Worker)
+				}
+			}
+			if bc.Length() != 1 {
+				t.Fatalf("aborted search changed the chain to %d blocks", bc.Length())
+			}
+		})
+	}
+}
+
+func TestCompareAndAppend(t *testing.T) {
+	tests := []struct {
+		name string
+		// advance mines a block on the chain after the candidate was built
+		advance       bool
+		wantCommitted bool
+	}{
+		{name: "tip unchanged", wantCommitted: true},
+		{name: "stale tip", advance: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestChain(t)
+			// Mined on the same genesis by another chain, like a candidate
+			// mined outside the lock
+			candidate := mineTransactions(t, newTestChain(t), 1)
+			if tt.advance {
+				mineTransactions(t, bc, 1)
+			}
+			tip := bc.Tip()
+
+			committed, _, _, err := bc.compareAndAppend(candidate)
+			if err != nil {
+				t.Fatalf("compareAndAppend: %v", err)
+			}
+			if committed != tt.wantCommitted {
+				t.Fatalf("committed = %t, want %t", committed, tt.wantCommitted)
+			}
+			if !committed && (bc.Tip().Hash != tip.Hash || bc.HasBlock(candidate.Hash)) {
+				t.Fatal("a candidate on a stale tip changed the chain")
+			}
+			if committed && bc.Tip().Hash != candidate.Hash {
+				t.Fatalf("tip = %s, want the candidate %s", bc.Tip().Hash, candidate.Hash)
+			}
+		})
+	}
+}
+
+func TestMineOptimisticRetriesOnStaleTip(t *testing.T) {
+	tests := []struct {
+		name string
+		// compete submits a block on the same parent while the first search runs
+		compete     bool
+		wantRetries int
+	}{
+		{name: "tip unchanged"},
+		{name: "tip moved during the search", compete: true, wantRetries: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bc := newTestChain(t)
+			competitor := mineTransactions(t, newTestChain(t), 1)
+			hasher := &gatedHasher{Hasher: bc.params.Hasher, searching: make(chan struct{}), resume: make(chan struct{})}
+			bc.params.Hasher = hasher
+
+			type result struct {
+				block Block
+				stats CommitStats
+				err   error
+			}
+			done := make(chan result, 1)
+			go func() {
+				block, stats, err := bc.MineOptimistic(context.Background(), Payload{Data: "optimistic"})
+				done <- result{block: block, stats: stats, err: err}
+			}()
+
+			<-hasher.searching
+			if tt.compete {
+				if _, err := bc.SubmitBlock(competitor); err != nil {
+					t.Fatalf("SubmitBlock: %v", err)
+				}
+			}
+			close(hasher.resume)
+
+			res := <-done
+			if res.err != nil {
+				t.Fatalf("MineOptimistic: %v", res.err)
+			}
+			if bc.Tip().Hash != res.block.Hash || res.stats.Index != res.block.Index {
+				t.Fatalf("tip %s, want the mined block %s", bc.Tip().Hash, res.block.Hash)
+			}
+			if tt.compete && res.block.PreviousHash != competitor.Hash {
+				t.Fatalf("retry mined on %s, want the new tip %s", res.block.PreviousHash, competitor.Hash)
+			}
+
+			stats := res.stats
+			if stats.Retries != tt.wantRetries {
+				t.Fatalf("retries = %d, want %d", stats.Retries, tt.wantRetries)
+			}
+			// The stale search is wasted, and counted along with the committed one
+			if (stats.Retries == 0) != (stats.WastedHashes == 0) || stats.WastedHashes >= stats.Hashes {
+				t.Fatalf("%d of %d hashes wasted over %d retries", stats.WastedHashes, stats.Hashes, stats.Retries)
+			}
+		})
+	}
+}
+
+func TestMineParallelOptimisticCommitsEveryGoroutine(t *testing.T) {
+	for _, goroutines := range []int{1, 4, 16} {
+		t.Run(fmt.Sprintf("%d goroutines", goroutines), func(t *testing.T) {
+			bc := newTestChain(t)
+
+			blocks, commits, _, err := bc.MineParallelOptimistic(context.Background(), Payload{Data: "optimistic"}, goroutines)
+			if err != nil {
+				t.Fatalf("MineParallelOptimistic: %v", err)
+			}
+			if len(blocks) != goroutines || len(commits) != goroutines || bc.Length() != goroutines+1 {
+				t.Fatalf("%d blocks and %d stats on a chain of %d, want %d", len(blocks), len(commits), bc.Length(), goroutines)
+			}
+			if report := bc.Validate(); !report.Valid {
+				t.Fatalf("chain failed validation: %+v", report.Errors)
+			}
+
+			workers := make(map[int]bool)
+			for i, stats := range commits {
+				if stats.Index != blocks[i].Index {
+					t.Errorf("stats of block %d report index %d", blocks[i].Index, stats.Index)
+				}
+				workers[stats.Worker] = true
+
+				// Every retry throws away a whole search, and the committed
+				// one is never wasted
+				if (stats.Retries == 0) != (stats.WastedHashes == 0) {
+					t.Errorf("worker %d: %d retries wasted %d hashes", stats.Worker, stats.Retries, stats.WastedHashes)
+				}
+				if stats.WastedHashes < stats.Retries || stats.WastedHashes >= stats.Hashes {
+					t.Errorf("worker %d: %d of %d hashes wasted over %d retries", stats.Worker, stats.WastedHashes, stats.Hashes, stats.Retries)
+				}
+			}
+			if len(workers) != goroutines {
+				t.Fatalf("blocks committed by %d distinct workers, want %d", len(workers), goroutines)
+			}
+		})
+	}
+}
+
+// gatedHasher blocks the first Sum, the first nonce of a search, until resume
+// is closed, so a test can change the chain while the search runs
+type gatedHasher struct {
+	Hasher
+	searching chan struct{}
+	resume    chan struct{}
+	gated     atomic.Bool
+}
+
+func (h *gatedHasher) Sum(data []byte) [HashSize]byte {
+	// Later hashes, such as the validation of a competing block, pass through
+	if h.gated.CompareAndSwap(false, true) {
+		close(h.searching)
+		<-h.resume
+	}
+	return h.Hasher.Sum(data)
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	// DifficultyHex counts leading zero hex characters of the hash, so every
+	// step is a 16x change in expected work
+	DifficultyHex DifficultyMode = "hex"
+	// DifficultyBits counts leading zero bits of the raw digest, so every
+	// step doubles the expected work
+	DifficultyBits DifficultyMode = "bits"
+
+	MinDifficulty = 1
+)
+
+var ErrInvalidDifficultyMode = errors.New("invalid difficulty mode")
+
+// DifficultyMode selects the unit a block difficulty is expressed in. Both
+// modes are checked against the raw digest: d hex zeros are 4*d zero bits.
+type DifficultyMode string
+
+func ParseDifficultyMode(value string) (DifficultyMode, error) {
+	switch mode := DifficultyMode(value); mode {
+	case DifficultyHex, DifficultyBits:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidDifficultyMode, value)
+	}
+}
+
+// bitsPerUnit is the number of leading zero bits one difficulty unit requires
+func (m DifficultyMode) bitsPerUnit() int {
+	if m == DifficultyBits {
+		return 1
+	}
+	return 4
+}
+
+// MaxDifficulty is the difficulty at which every bit of the digest must be zero
+func (m DifficultyMode) MaxDifficulty() int {
+	return HashSize * 8 / m.bitsPerUnit()
+}
+
+// stepFactor is how much more work one extra difficulty unit requires
+func (m DifficultyMode) stepFactor() float64 {
+	return math.Exp2(float64(m.bitsPerUnit()))
+}
+
+// ZeroBits returns the leading zero bits a hash needs to satisfy difficulty
+func (m DifficultyMode) ZeroBits(difficulty int) int {
+	return difficulty * m.bitsPerUnit()
+}
+
+// ExpectedHashes is the average number of hashes needed to mine a block at
+// difficulty, i.e. 2^zeroBits
+func (m DifficultyMode) ExpectedHashes(difficulty int) float64 {
+	return math.Exp2(float64(m.ZeroBits(difficulty)))
+}
+
+func (m DifficultyMode) clamp(difficulty int) int {
+	return max(MinDifficulty, min(m.MaxDifficulty(), difficulty))
+}
+
+func leadingZeroBits(digest []byte) int {
+	n := 0
+	for _, b := range digest {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+func hashMeetsTarget(hash Hash, zeroBits int) bool {
+	return leadingZeroBits(hash[:]) >= zeroBits
+}
+
+// hexMeetsTarget checks a hex-encoded hash the way hashes were checked while
+// they were kept as strings: its first characters against target, a run of
+// zeroBits/4 zeros, then the bits left over against the next character
+func hexMeetsTarget(encoded, target string, zeroBits int) bool {
+	if encoded[:len(target)] != target {
+		return false
+	}
+	rest := zeroBits % 4
+	if rest == 0 {
+		return true
+	}
+	nibble, err := strconv.ParseUint(encoded[len(target):len(target)+1], 16, 8)
+	return err == nil && nibble < 1<<(4-rest)
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// maxRetargetZeroBits caps a single retarget to a 16x change in work, i.e.
+// one hex zero or four bits
+const maxRetargetZeroBits = 4
+
+type (
+	// RetargetPolicy adjusts difficulty every Interval blocks so that blocks
+	// are mined roughly every TargetBlockTime. An Interval of 0 keeps the
+	// difficulty fixed.
+	RetargetPolicy struct {
+		Interval        int
+		TargetBlockTime time.Duration
+	}
+
+	// Params are the consensus rules a chain is mined and validated with
+	Params struct {
+		Difficulty  int
+		Mode        DifficultyMode
+		Retarget    RetargetPolicy
+		BlockReward uint64
+		// Hasher is the proof-of-work digest, recorded by the genesis block
+		Hasher Hasher
+	}
+
+	ChainInfo struct {
+		Height             int     `json:"height"`
+		Length             int     `json:"length"`
+		TipHash            Hash    `json:"tip_hash"`
+		Difficulty         int     `json:"difficulty"`
+		DifficultyMode     string  `json:"difficulty_mode"`
+		HashAlgorithm      string  `json:"hash_algorithm"`
+		ExpectedHashes     float64 `json:"expected_hashes"`
+		TipDifficulty      int     `json:"tip_difficulty"`
+		BaseDifficulty     int     `json:"base_difficulty"`
+		RetargetEnabled    bool    `json:"retarget_enabled"`
+		RetargetInterval   int     `json:"retarget_interval"`
+		TargetBlockTime    string  `json:"target_block_time,omitempty"`
+		NextRetargetHeight int     `json:"next_retarget_height"`
+		EncodingVersion    uint8   `json:"encoding_version"`
+		BlockReward        uint64  `json:"block_reward"`
+		Accounts           int     `json:"accounts"`
+		TotalWork          float64 `json:"total_work"`
+		KnownBlocks        int     `json:"known_blocks"`
+		Reorgs             int     `json:"reorgs"`
+	}
+)
+
+// Enabled reports whether the policy ever changes the difficulty. At least two
+// blocks are needed in a window to measure a block time.
+func (p RetargetPolicy) Enabled() bool {
+	return p.Interval >= 2 && p.TargetBlockTime > 0
+}
+
+// NextRetargetHeight returns the first retarget height after height, or -1
+// when retargeting is disabled. The first retarget happens at 2*Interval so
+// the genesis timestamp never enters the measured window.
+func (p RetargetPolicy) NextRetargetHeight(height int) int {
+	if !p.Enabled() {
+		return -1
+	}
+
+	next := (height/p.Interval + 1) * p.Interval
+	if next < 2*p.Interval {
+		next = 2 * p.Interval
+	}
+	return next
+}
+
+// effectiveDifficulty returns the difficulty a block was mined at. Blocks
+// encoded before V2 do not record it and were mined at the base difficulty.
+func (p Params) effectiveDifficulty(block Block) int {
+	if block.Version < EncodingV2 {
+		return p.Difficulty
+	}
+	return block.Difficulty
+}
+
+// nextDifficulty returns the difficulty required for the block following
+// ancestors, which must be the chain from genesis up to the parent
+func (p Params) nextDifficulty(ancestors []Block) int {
+	parent := ancestors[len(ancestors)-1]
+	current := p.effectiveDifficulty(parent)
+	height := len(ancestors)
+
+	if !p.Retarget.Enabled() || height%p.Retarget.Interval != 0 || height < 2*p.Retarget.Interval {
+		return current
+	}
+
+	first := ancestors[len(ancestors)-p.Retarget.Interval]
+	actual := parent.Timestamp.Sub(first.Timestamp)
+	expected := p.Retarget.TargetBlockTime * time.Duration(p.Retarget.Interval-1)
+
+	return retarget(p.Mode, current, expected, actual)
+}
+
+// retarget moves the difficulty by the number of units that best matches the
+// ratio between the expected and the actual window duration
+func retarget(mode DifficultyMode, current int, expected, actual time.Duration) int {
+	if actual <= 0 {
+		actual = time.Nanosecond
+	}
+
+	maxStep := maxRetargetZeroBits / mode.bitsPerUnit()
+	ratio := float64(expected) / float64(actual)
+	step := int(math.Round(math.Log(ratio) / math.Log(mode.stepFactor())))
+	step = max(-maxStep, min(maxStep, step))
+
+	return mode.clamp(current + step)
+}
+
+// Info describes the tip and the difficulty rules of the chain
+func (bc *Blockchain) Info() ChainInfo {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	tip := bc.chain[len(bc.chain)-1]
+	difficulty := bc.params.nextDifficulty(bc.chain)
+	info := ChainInfo{
+		Height:             tip.Index,
+		Length:             len(bc.chain),
+		TipHash:            tip.Hash,
+		Difficulty:         difficulty,
+		DifficultyMode:     string(bc.params.Mode),
+		HashAlgorithm:      string(bc.params.Hasher.Algorithm()),
+		ExpectedHashes:     bc.params.Mode.ExpectedHashes(difficulty),
+		TipDifficulty:      bc.params.effectiveDifficulty(tip),
+		BaseDifficulty:     bc.params.Difficulty,
+		RetargetEnabled:    bc.params.Retarget.Enabled(),
+		RetargetInterval:   bc.params.Retarget.Interval,
+		NextRetargetHeight: bc.params.Retarget.NextRetargetHeight(tip.Index),
+		EncodingVersion:    CurrentEncoding,
+		BlockReward:        bc.params.BlockReward,
+		Accounts:           bc.ledger.Len(),
+		TotalWork:          bc.tip.work,
+		KnownBlocks:        len(bc.nodes),
+		Reorgs:             bc.reorgs,
+	}
+	if info.RetargetEnabled {
+		info.TargetBlockTime = bc.params.Retarget.TargetBlockTime.String()
+	}
+
+	return info
+}
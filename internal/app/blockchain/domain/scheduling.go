@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"time"
+)
+
+const (
+	// YieldNone never yields: the search is only descheduled by asynchronous
+	// preemption, which sysmon requests after about 10ms on the CPU
+	YieldNone YieldStrategy = "none"
+	// YieldGosched calls runtime.Gosched every YieldInterval nonces, putting
+	// the goroutine back on the global run queue
+	YieldGosched YieldStrategy = "gosched"
+	// YieldSleep calls time.Sleep(time.Nanosecond) every YieldInterval nonces.
+	// The runtime returns right away from a non-positive sleep, so the
+	// shortest positive one is used: it parks the goroutine on a timer and
+	// other goroutines run until the timer fires.
+	YieldSleep YieldStrategy = "sleep"
+	// YieldLockOSThread wires the searching goroutine to its OS thread and
+	// yields like YieldGosched. Every yield then parks the thread and hands
+	// its P to another one, so each yield costs two thread switches.
+	YieldLockOSThread YieldStrategy = "lock-os-thread"
+
+	// DefaultYieldInterval is the number of nonces tried between two yields
+	DefaultYieldInterval = 100000
+
+	// DefaultProbeInterval is how long the scheduling probe sleeps between wakeups
+	DefaultProbeInterval = time.Millisecond
+	// maxProbeSamples bounds the wakeup delays a probe keeps, about four
+	// seconds of mining at DefaultProbeInterval. They are allocated up front
+	// so the probe does not allocate while it runs.
+	maxProbeSamples = 4096
+)
+
+var ErrInvalidYieldStrategy = errors.New("invalid yield strategy")
+
+type (
+	// YieldStrategy selects how the nonce search gives the CPU back to the scheduler
+	YieldStrategy string
+
+	// SchedulingProbe is a goroutine that sleeps for a fixed interval in a
+	// loop and records how late it wakes up. While CPU-bound goroutines
+	// occupy every P, the delay is how long the scheduler takes to run it.
+	// Wakeups after the first maxProbeSamples are counted but not recorded.
+	SchedulingProbe struct {
+		interval time.Duration
+		delays   []time.Duration
+		wakeups  int
+		stop     chan struct{}
+		done     chan struct{}
+	}
+
+	ProbeStats struct {
+		IntervalMs float64 `json:"interval_ms"`
+		// GOMAXPROCS is the number of Ps the probe competes for
+		GOMAXPROCS int `json:"gomaxprocs"`
+		Wakeups    int `json:"wakeups"`
+		// Samples is the number of wakeups the delays are computed from
+		Samples     int     `json:"samples"`
+		MeanDelayMs float64 `json:"mean_delay_ms"`
+		P50DelayMs  float64 `json:"p50_delay_ms"`
+		P99DelayMs  float64 `json:"p99_delay_ms"`
+		MaxDelayMs  float64 `json:"max_delay_ms"`
+	}
+)
+
+// YieldStrategies lists the supported strategies
+func YieldStrategies() []YieldStrategy {
+	return []YieldStrategy{YieldNone, YieldGosched, YieldSleep, YieldLockOSThread}
+}
+
+func ParseYieldStrategy(value string) (YieldStrategy, error) {
+	switch strategy := YieldStrategy(value); strategy {
+	case YieldNone, YieldGosched, YieldSleep, YieldLockOSThread:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected one of %v)", ErrInvalidYieldStrategy, value, YieldStrategies())
+	}
+}
+
+// yield gives the CPU back according to strategy. YieldNone never gets here.
+func (strategy YieldStrategy) yield() {
+	if strategy == YieldSleep {
+		time.Sleep(time.Nanosecond)
+		return
+	}
+	runtime.Gosched()
+}
+
+// StartSchedulingProbe starts a probe that wakes up every interval until Stop
+func StartSchedulingProbe(interval time.Duration) *SchedulingProbe {
+	p := &SchedulingProbe{
+		interval: interval,
+		delays:   make([]time.Duration, 0, maxProbeSamples),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *SchedulingProbe) run() {
+	defer close(p.done)
+
+	timer := time.NewTimer(p.interval)
+	defer timer.Stop()
+
+	for {
+		start := time.Now()
+		timer.Reset(p.interval)
+		select {
+		case <-p.stop:
+			return
+		case <-timer.C:
+		}
+		p.wakeups++
+		if len(p.delays) < cap(p.delays) {
+			p.delays = append(p.delays, time.Since(start)-p.interval)
+		}
+	}
+}
+
+// Stop ends the probe and summarizes the wakeup delays it recorded
+func (p *SchedulingProbe) Stop() ProbeStats {
+	close(p.stop)
+	<-p.done
+
+	stats := ProbeStats{
+		IntervalMs: milliseconds(p.interval),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Wakeups:    p.wakeups,
+		Samples:    len(p.delays),
+	}
+	if len(p.delays) == 0 {
+		return stats
+	}
+
+	slices.Sort(p.delays)
+	var total time.Duration
+	for _, d := range p.delays {
+		total += d
+	}
+	stats.MeanDelayMs = milliseconds(total / time.Duration(len(p.delays)))
+	stats.P50DelayMs = milliseconds(p.delays[len(p.delays)/2])
+	stats.P99DelayMs = milliseconds(p.delays[len(p.delays)*99/100])
+	stats.MaxDelayMs = milliseconds(p.delays[len(p.delays)-1])
+	return stats
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseYieldStrategy(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    YieldStrategy
+		wantErr error
+	}{
+		{value: "none", want: YieldNone},
+		{value: "gosched", want: YieldGosched},
+		{value: "sleep", want: YieldSleep},
+		{value: "lock-os-thread", want: YieldLockOSThread},
+		{value: "", wantErr: ErrInvalidYieldStrategy},
+		{value: "Gosched", wantErr: ErrInvalidYieldStrategy},
+		{value: "spin", wantErr: ErrInvalidYieldStrategy},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseYieldStrategy(tt.value)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseYieldStrategy(%q) = %q, %v; want %q, %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestYieldStrategiesCountYields(t *testing.T) {
+	for _, strategy := range YieldStrategies() {
+		t.Run(string(strategy), func(t *testing.T) {
+			bc := newTestChain(t)
+
+			// Yielding after every nonce but the winning one
+			options := MiningOptions{Yield: strategy, YieldInterval: 1}
+			_, stats, err := bc.AddBlock(context.Background(), Payload{Data: "yield", Mining: options})
+			if err != nil {
+				t.Fatalf("AddBlock: %v", err)
+			}
+			want := stats.Hashes - 1
+			if strategy == YieldNone {
+				want = 0
+			}
+			if stats.Yields != want {
+				t.Fatalf("yields = %d over %d hashes, want %d", stats.Yields, stats.Hashes, want)
+			}
+		})
+	}
+}
+
+func TestSchedulingProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		run      time.Duration
+		// wantWakeups is false when the probe stops before its first wakeup
+		wantWakeups bool
+	}{
+		{name: "stopped before the first wakeup", interval: time.Hour},
+		{name: "default interval", interval: DefaultProbeInterval, run: 20 * time.Millisecond, wantWakeups: true},
+		// Wakes up as fast as the timers fire, past the recorded samples
+		{name: "more wakeups than samples", interval: time.Nanosecond, run: 200 * time.Millisecond, wantWakeups: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := StartSchedulingProbe(tt.interval)
+			time.Sleep(tt.run)
+			stats := probe.Stop()
+
+			if stats.IntervalMs != milliseconds(tt.interval) || stats.GOMAXPROCS < 1 {
+				t.Fatalf("interval %vms on %d Ps, want %vms", stats.IntervalMs, stats.GOMAXPROCS, milliseconds(tt.interval))
+			}
+			if (stats.Wakeups > 0) != tt.wantWakeups {
+				t.Fatalf("wakeups = %d, want some: %t", stats.Wakeups, tt.wantWakeups)
+			}
+			if want := min(stats.Wakeups, maxProbeSamples); stats.Samples != want {
+				t.Fatalf("samples = %d of %d wakeups, want %d", stats.Samples, stats.Wakeups, want)
+			}
+
+			if stats.Samples == 0 {
+				if stats != (ProbeStats{IntervalMs: stats.IntervalMs, GOMAXPROCS: stats.GOMAXPROCS}) {
+					t.Fatalf("stats without samples = %+v, want zero delays", stats)
+				}
+				return
+			}
+			if stats.P50DelayMs > stats.P99DelayMs || stats.P99DelayMs > stats.MaxDelayMs || stats.MeanDelayMs > stats.MaxDelayMs {
+				t.Fatalf("delays out of order: %+v", stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// AddressSize is the number of bytes of the public key digest kept in an address
+const AddressSize = 20
+
+var ErrInvalidAddress = errors.New("invalid address")
+
+// AddressFromPublicKey derives an account address: the hex encoding of the
+// first AddressSize bytes of the SHA-256 of the public key
+func AddressFromPublicKey(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:AddressSize])
+}
+
+// IsAddress reports whether s is a lowercase hex address of AddressSize bytes
+func IsAddress(s string) bool {
+	if len(s) != 2*AddressSize {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// SignTransaction sets the sender of tx to the owner of privateKey, signs the
+// canonical encoding and derives the ID
+func SignTransaction(tx Transaction, privateKey ed25519.PrivateKey) Transaction {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	tx.Timestamp = tx.Timestamp.Round(0)
+	tx.From = AddressFromPublicKey(publicKey)
+	tx.PublicKey = hex.EncodeToString(publicKey)
+	tx.Signature = hex.EncodeToString(ed25519.Sign(privateKey, EncodeTransaction(tx)))
+	tx.ID = tx.ComputeID()
+
+	return tx
+}
+
+// verifySignature returns an empty code when the public key matches From and
+// the signature is valid for the canonical encoding
+func verifySignature(tx Transaction) (TransactionErrorCode, string) {
+	if tx.From == "" || tx.PublicKey == "" || tx.Signature == "" {
+		return TxMissingSignature, "signed transactions need from, public_key and signature"
+	}
+
+	publicKey, err := hex.DecodeString(tx.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return TxInvalidPublicKey, fmt.Sprintf("public_key must be %d hex-encoded bytes", ed25519.PublicKeySize)
+	}
+
+	if address := AddressFromPublicKey(publicKey); address != tx.From {
+		return TxAddressMismatch, fmt.Sprintf("from %q does not match the address of public_key %q", tx.From, address)
+	}
+
+	signature, err := hex.DecodeString(tx.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return TxInvalidSignature, fmt.Sprintf("signature must be %d hex-encoded bytes", ed25519.SignatureSize)
+	}
+
+	if !ed25519.Verify(publicKey, EncodeTransaction(tx), signature) {
+		return TxInvalidSignature, "signature does not verify against public_key"
+	}
+
+	return "", ""
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"slices"
+	"sync"
+)
+
+type (
+	// BlockStore persists blocks in append order. Load is called once when the
+	// blockchain starts and must return every block previously appended.
+	// Append stores blocks and Replace swaps every stored block for blocks,
+	// each at once: after a failure, Load still returns the previous blocks.
+	BlockStore interface {
+		Load() ([]Block, error)
+		Append(blocks ...Block) error
+		Replace(blocks []Block) error
+		Close() error
+	}
+
+	// MemoryStore keeps blocks only for the lifetime of the process
+	MemoryStore struct {
+		blocks []Block
+		mu     sync.Mutex
+	}
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Load() ([]Block, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	blocks := make([]Block, len(s.blocks))
+	copy(blocks, s.blocks)
+	return blocks, nil
+}
+
+func (s *MemoryStore) Append(blocks ...Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocks = append(s.blocks, blocks...)
+	return nil
+}
+
+func (s *MemoryStore) Replace(blocks []Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blocks = slices.Clone(blocks)
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
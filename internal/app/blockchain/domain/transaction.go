@@ -0,0 +1,286 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	TxIDMismatch        TransactionErrorCode = "id_mismatch"
+	TxDuplicateID       TransactionErrorCode = "duplicate_id"
+	TxMissingSignature  TransactionErrorCode = "missing_signature"
+	TxInvalidPublicKey  TransactionErrorCode = "invalid_public_key"
+	TxAddressMismatch   TransactionErrorCode = "address_mismatch"
+	TxInvalidSignature  TransactionErrorCode = "invalid_signature"
+	TxInvalidTransfer   TransactionErrorCode = "invalid_transfer"
+	TxInvalidCoinbase   TransactionErrorCode = "invalid_coinbase"
+	TxInvalidNonce      TransactionErrorCode = "invalid_nonce"
+	TxInsufficientFunds TransactionErrorCode = "insufficient_funds"
+)
+
+var ErrInvalidTransaction = errors.New("invalid transaction")
+
+type (
+	// Transaction is either an unsigned data record or, when From is set, a
+	// transaction signed by the ed25519 key whose address is From. Setting To
+	// makes it a transfer of Amount: signed transfers move funds between
+	// accounts and pay Fee to the miner, an unsigned one is the coinbase that
+	// mints the block reward and collects the fees.
+	Transaction struct {
+		ID        string    `json:"id"`
+		Payload   string    `json:"payload"`
+		Timestamp time.Time `json:"timestamp"`
+		From      string    `json:"from,omitempty"`
+		PublicKey string    `json:"public_key,omitempty"`
+		Signature string    `json:"signature,omitempty"`
+		To        string    `json:"to,omitempty"`
+		Amount    uint64    `json:"amount,omitempty"`
+		Nonce     uint64    `json:"nonce,omitempty"`
+		Fee       uint64    `json:"fee,omitempty"`
+	}
+
+	// Payload is the content carried by a newly mined block: the legacy
+	// opaque data string, a list of transactions, or both. Miner overrides
+	// the address credited with the block reward. FromMempool appends the
+	// highest-fee pending transactions that fit in the block. Mining
+	// selects how the block is mined and is not part of it.
+	Payload struct {
+		Data         string
+		Transactions []Transaction
+		Miner        string
+		FromMempool  bool
+		Mining       MiningOptions
+	}
+
+	TransactionErrorCode string
+
+	// TransactionError reports why a transaction was rejected. It wraps
+	// ErrInvalidTransaction and is meant to be returned to clients as is.
+	TransactionError struct {
+		TxIndex int                  `json:"tx_index"`
+		TxID    string               `json:"tx_id"`
+		Code    TransactionErrorCode `json:"code"`
+		Reason  string               `json:"reason"`
+	}
+)
+
+// NewTransaction creates a transaction and derives its ID from the canonical encoding
+func NewTransaction(payload string, timestamp time.Time) Transaction {
+	tx := Transaction{
+		Payload:   payload,
+		Timestamp: timestamp.Round(0),
+	}
+	tx.ID = tx.ComputeID()
+	return tx
+}
+
+// EncodeTransaction returns the canonical bytes a transaction ID is derived
+// from and a signature is computed over:
+//
+//	timestamp          int64 big-endian (UnixNano)
+//	payload length     uint32 big-endian
+//	payload            raw bytes
+//
+// Signed transactions append the sender, so the signature commits to it:
+//
+//	from length        uint32 big-endian
+//	from               address, hex
+//	public key length  uint32 big-endian
+//	public key         hex
+//
+// Transfers then append:
+//
+//	to length          uint32 big-endian
+//	to                 address, hex
+//	amount             uint64 big-endian
+//	nonce              uint64 big-endian
+//
+// and, when the fee is not zero:
+//
+//	fee                uint64 big-endian
+//
+// The signature itself is never part of the encoding.
+func EncodeTransaction(tx Transaction) []byte {
+	size := 8 + 4 + len(tx.Payload)
+	if tx.hasSender() {
+		size += 4 + len(tx.From) + 4 + len(tx.PublicKey)
+	}
+	if tx.IsTransfer() {
+		size += 4 + len(tx.To) + 8 + 8
+	}
+	if tx.Fee != 0 {
+		size += 8
+	}
+
+	dst := make([]byte, 0, size)
+	dst = binary.BigEndian.AppendUint64(dst, uint64(tx.Timestamp.UnixNano()))
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(tx.Payload)))
+	dst = append(dst, tx.Payload...)
+
+	if tx.hasSender() {
+		dst = binary.BigEndian.AppendUint32(dst, uint32(len(tx.From)))
+		dst = append(dst, tx.From...)
+		dst = binary.BigEndian.AppendUint32(dst, uint32(len(tx.PublicKey)))
+		dst = append(dst, tx.PublicKey...)
+	}
+
+	if tx.IsTransfer() {
+		dst = binary.BigEndian.AppendUint32(dst, uint32(len(tx.To)))
+		dst = append(dst, tx.To...)
+		dst = binary.BigEndian.AppendUint64(dst, tx.Amount)
+		dst = binary.BigEndian.AppendUint64(dst, tx.Nonce)
+	}
+
+	if tx.Fee != 0 {
+		dst = binary.BigEndian.AppendUint64(dst, tx.Fee)
+	}
+
+	return dst
+}
+
+// ComputeID returns the hex SHA-256 of the canonical encoding
+func (tx Transaction) ComputeID() string {
+	sum := sha256.Sum256(EncodeTransaction(tx))
+	return hex.EncodeToString(sum[:])
+}
+
+// Cost returns what a signed transfer debits from the sender, reporting
+// false on overflow
+func (tx Transaction) Cost() (uint64, bool) {
+	if tx.Amount > math.MaxUint64-tx.Fee {
+		return 0, false
+	}
+	return tx.Amount + tx.Fee, true
+}
+
+// Signed reports whether the transaction claims a sender and must carry a valid signature
+func (tx Transaction) Signed() bool {
+	return tx.hasSender() || tx.Signature != ""
+}
+
+// IsTransfer reports whether the transaction moves or mints funds
+func (tx Transaction) IsTransfer() bool {
+	return tx.To != ""
+}
+
+// IsCoinbase reports whether the transaction mints the block reward
+func (tx Transaction) IsCoinbase() bool {
+	return tx.IsTransfer() && !tx.Signed()
+}
+
+func (tx Transaction) hasSender() bool {
+	return tx.From != "" || tx.PublicKey != ""
+}
+
+// VerifySignedTransaction checks the ID and signature of a transaction that
+// is required to be signed
+func VerifySignedTransaction(tx Transaction) error {
+	if !tx.Signed() {
+		return &TransactionError{TxID: tx.ID, Code: TxMissingSignature, Reason: "from, public_key and signature are required"}
+	}
+	if txErr := tx.check(0); txErr != nil {
+		return txErr
+	}
+	return nil
+}
+
+// check verifies the ID and, for signed transactions, the signature. index
+// is the position of the transaction in its block.
+func (tx Transaction) check(index int) *TransactionError {
+	if computed := tx.ComputeID(); computed != tx.ID {
+		return &TransactionError{
+			TxIndex: index,
+			TxID:    tx.ID,
+			Code:    TxIDMismatch,
+			Reason:  fmt.Sprintf("id %q does not match its content, computed %q", tx.ID, computed),
+		}
+	}
+
+	if code, reason := checkTransfer(tx); code != "" {
+		return &TransactionError{TxIndex: index, TxID: tx.ID, Code: code, Reason: reason}
+	}
+
+	if !tx.Signed() {
+		return nil
+	}
+
+	if code, reason := verifySignature(tx); code != "" {
+		return &TransactionError{TxIndex: index, TxID: tx.ID, Code: code, Reason: reason}
+	}
+	return nil
+}
+
+// checkTransfer validates the transfer fields, which are only committed to
+// by the encoding when To is set
+func checkTransfer(tx Transaction) (TransactionErrorCode, string) {
+	if !tx.IsTransfer() {
+		if tx.Amount != 0 || tx.Nonce != 0 || tx.Fee != 0 {
+			return TxInvalidTransfer, "amount, nonce and fee require to"
+		}
+		return "", ""
+	}
+
+	if tx.Fee != 0 && !tx.Signed() {
+		return TxInvalidTransfer, "only signed transfers pay a fee"
+	}
+
+	if !IsAddress(tx.To) {
+		return TxInvalidTransfer, fmt.Sprintf("to %q is not an address", tx.To)
+	}
+	if tx.Amount == 0 {
+		return TxInvalidTransfer, "amount must be positive"
+	}
+	return "", ""
+}
+
+// verify checks every transaction submitted for a new block. Signature
+// checks are the expensive part and need no chain state, so callers run it
+// before taking the chain lock.
+func (p Payload) verify() error {
+	if p.Miner != "" && !IsAddress(p.Miner) {
+		return fmt.Errorf("%w: miner %q is not an address", ErrInvalidAddress, p.Miner)
+	}
+	if p.Mining.HashImpl != "" {
+		if _, err := ParseHashImpl(string(p.Mining.HashImpl)); err != nil {
+			return err
+		}
+	}
+	if p.Mining.Yield != "" {
+		if _, err := ParseYieldStrategy(string(p.Mining.Yield)); err != nil {
+			return err
+		}
+	}
+	if p.Mining.YieldInterval < 0 {
+		return fmt.Errorf("%w: interval must not be negative", ErrInvalidYieldStrategy)
+	}
+
+	seen := make(map[string]struct{}, len(p.Transactions))
+
+	for i, tx := range p.Transactions {
+		if txErr := tx.check(i); txErr != nil {
+			return txErr
+		}
+		if tx.IsCoinbase() {
+			return &TransactionError{TxIndex: i, TxID: tx.ID, Code: TxInvalidCoinbase, Reason: "coinbase transactions are created by the miner"}
+		}
+		if _, ok := seen[tx.ID]; ok {
+			return &TransactionError{TxIndex: i, TxID: tx.ID, Code: TxDuplicateID, Reason: "duplicate id"}
+		}
+		seen[tx.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+func (e *TransactionError) Error() string {
+	return fmt.Sprintf("%s: transaction %d: %s", ErrInvalidTransaction, e.TxIndex, e.Reason)
+}
+
+func (e *TransactionError) Unwrap() error {
+	return ErrInvalidTransaction
+}
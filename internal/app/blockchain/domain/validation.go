@@ -0,0 +1,209 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ReasonIndexMismatch        ValidationCode = "index_mismatch"
+	ReasonPreviousHashMismatch ValidationCode = "previous_hash_mismatch"
+	ReasonHashMismatch         ValidationCode = "hash_mismatch"
+	ReasonDifficultyNotMet     ValidationCode = "difficulty_not_met"
+	ReasonUnexpectedDifficulty ValidationCode = "unexpected_difficulty"
+	ReasonEmptyChain           ValidationCode = "empty_chain"
+	ReasonInvalidEncoding      ValidationCode = "invalid_encoding"
+	ReasonInvalidTransaction   ValidationCode = "invalid_transaction"
+	ReasonMerkleRootMismatch   ValidationCode = "merkle_root_mismatch"
+	ReasonLedgerViolation      ValidationCode = "ledger_violation"
+)
+
+var ErrInvalidStoredChain = errors.New("stored chain failed validation")
+
+type (
+	// ValidationCode identifies the kind of integrity failure found in a block
+	ValidationCode string
+
+	ValidationError struct {
+		Index  int            `json:"index"`
+		Code   ValidationCode `json:"code"`
+		Reason string         `json:"reason"`
+	}
+
+	ValidationReport struct {
+		Valid            bool              `json:"valid"`
+		Length           int               `json:"length"`
+		FirstBrokenIndex *int              `json:"first_broken_index,omitempty"`
+		Errors           []ValidationError `json:"errors"`
+	}
+)
+
+// Validate recomputes every block hash and checks linkage, index continuity
+// and proof-of-work, reporting every failure found
+func (bc *Blockchain) Validate() ValidationReport {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	report, _ := validateChain(bc.chain, bc.params)
+	return report
+}
+
+// validateChain checks every block of chain against the ones before it and
+// replays their transfers. The ledger after the last block is returned when
+// the chain is valid, so callers adopting the chain need not replay it again.
+func validateChain(chain []Block, params Params) (ValidationReport, *Ledger) {
+	report := ValidationReport{
+		Length: len(chain),
+		Errors: make([]ValidationError, 0),
+	}
+
+	if len(chain) == 0 {
+		report.Errors = append(report.Errors, ValidationError{
+			Index:  0,
+			Code:   ReasonEmptyChain,
+			Reason: "chain has no genesis block",
+		})
+	}
+
+	ledger := newLedger()
+	for i, block := range chain {
+		report.Errors = append(report.Errors, validateBlock(i, block, chain[:i], params)...)
+
+		if err := ledger.apply(block, params.BlockReward); err != nil {
+			report.Errors = append(report.Errors, ValidationError{
+				Index:  i,
+				Code:   ReasonLedgerViolation,
+				Reason: err.Error(),
+			})
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		first := report.Errors[0].Index
+		report.FirstBrokenIndex = &first
+	}
+	report.Valid = len(report.Errors) == 0
+	if !report.Valid {
+		return report, nil
+	}
+
+	return report, ledger
+}
+
+// validateBlock checks a single block at the given position against the
+// blocks before it. The genesis block (no ancestors) is not mined, so only its
+// index and hash are verified.
+func validateBlock(position int, block Block, ancestors []Block, params Params) []ValidationError {
+	var errs []ValidationError
+
+	var previous *Block
+	if len(ancestors) > 0 {
+		previous = &ancestors[len(ancestors)-1]
+	}
+
+	if block.Index != position {
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonIndexMismatch,
+			Reason: fmt.Sprintf("expected index %d, got %d", position, block.Index),
+		})
+	}
+
+	if previous != nil && block.PreviousHash != previous.Hash {
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonPreviousHashMismatch,
+			Reason: fmt.Sprintf("previous_hash %q does not match hash %q of block %d", block.PreviousHash, previous.Hash, previous.Index),
+		})
+	}
+
+	computed, err := calculateHash(block, params.Hasher)
+	switch {
+	case err != nil:
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonInvalidEncoding,
+			Reason: err.Error(),
+		})
+	case computed != block.Hash:
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonHashMismatch,
+			Reason: fmt.Sprintf("stored hash %q, computed %q", block.Hash, computed),
+		})
+	}
+
+	errs = append(errs, validateTransactions(position, block)...)
+
+	if previous == nil {
+		return errs
+	}
+
+	difficulty := params.effectiveDifficulty(block)
+	if block.Version >= EncodingV2 {
+		if expected := params.nextDifficulty(ancestors); block.Difficulty != expected {
+			errs = append(errs, ValidationError{
+				Index:  position,
+				Code:   ReasonUnexpectedDifficulty,
+				Reason: fmt.Sprintf("expected difficulty %d, got %d", expected, block.Difficulty),
+			})
+		}
+	}
+
+	if zeroBits := params.Mode.ZeroBits(difficulty); !hashMeetsTarget(block.Hash, zeroBits) {
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonDifficultyNotMet,
+			Reason: fmt.Sprintf("hash %q does not have %d leading zero bits (difficulty %d %s)", block.Hash, zeroBits, difficulty, params.Mode),
+		})
+	}
+
+	return errs
+}
+
+// validateTransactions recomputes every transaction ID, verifies signatures
+// and checks the Merkle root committed to by a V3 header
+func validateTransactions(position int, block Block) []ValidationError {
+	if block.Version < EncodingV3 {
+		return nil
+	}
+
+	var errs []ValidationError
+	seen := make(map[string]struct{}, len(block.Transactions))
+
+	for i, tx := range block.Transactions {
+		if txErr := tx.check(i); txErr != nil {
+			errs = append(errs, ValidationError{
+				Index:  position,
+				Code:   ReasonInvalidTransaction,
+				Reason: fmt.Sprintf("transaction %d: %s: %s", i, txErr.Code, txErr.Reason),
+			})
+		}
+		if _, ok := seen[tx.ID]; ok {
+			errs = append(errs, ValidationError{
+				Index:  position,
+				Code:   ReasonInvalidTransaction,
+				Reason: fmt.Sprintf("transaction %d: duplicate id %q", i, tx.ID),
+			})
+		}
+		seen[tx.ID] = struct{}{}
+	}
+
+	root, err := MerkleRoot(block.Transactions)
+	switch {
+	case err != nil:
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonMerkleRootMismatch,
+			Reason: err.Error(),
+		})
+	case !block.MerkleRoot.Valid || root != block.MerkleRoot.Hash:
+		errs = append(errs, ValidationError{
+			Index:  position,
+			Code:   ReasonMerkleRootMismatch,
+			Reason: fmt.Sprintf("merkle_root %q, computed %q", block.MerkleRoot, root),
+		})
+	}
+
+	return errs
+}
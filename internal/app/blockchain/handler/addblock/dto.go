@@ -1,5 +1,25 @@
 package addblock
 
-type InputPayload struct {
-	Data string `json:"data"`
-}
+import "time"
+
+type (
+	// InputPayload accepts either the legacy data string or a list of
+	// transactions, optionally completed with pending ones
+	InputPayload struct {
+		Data          string               `json:"data"`
+		Transactions  []TransactionPayload `json:"transactions"`
+		Miner         string               `json:"miner"`          // optional address credited with the block reward
+		FromMempool   bool                 `json:"from_mempool"`   // append the best pending transactions
+		ParentHash    string               `json:"parent_hash"`    // optional, mine on this block instead of the tip
+		TimeoutMs     int                  `json:"timeout_ms"`     // optional mining deadline in milliseconds
+		HashImpl      string               `json:"hash_impl"`      // "naive" or "optimized" (default: "optimized")
+		Yield         string               `json:"yield"`          // "none", "gosched", "sleep" or "lock-os-thread" (default: "gosched")
+		YieldInterval int                  `json:"yield_interval"` // nonces between yields (default: 100000)
+		Probe         bool                 `json:"probe"`          // measure scheduling delays with a probe goroutine
+	}
+
+	TransactionPayload struct {
+		Payload   string    `json:"payload"`
+		Timestamp time.Time `json:"timestamp"` // optional, defaults to the time the block is requested
+	}
+)
@@ -1,8 +1,12 @@
 package addblock
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
+	"go-runtime-demo/internal/app/blockchain/domain"
 	"go-runtime-demo/internal/app/blockchain/usecase/addblock"
 	httpjson "go-runtime-demo/pkg/http"
 
@@ -11,6 +15,8 @@ import (
 
 const Path = "/blocks"
 
+var ErrDataAndTransactions = errors.New("provide either data or transactions, not both")
+
 type Handler struct {
 	useCase addblock.UseCase
 }
@@ -30,11 +36,111 @@ func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if payload.Data == "" {
+	if payload.Data == "" && len(payload.Transactions) == 0 && !payload.FromMempool {
 		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
 		return
 	}
 
-	result := h.useCase.Execute(r.Context(), payload.Data)
+	if payload.Data != "" && len(payload.Transactions) > 0 {
+		httpjson.WriteError(w, http.StatusBadRequest, ErrDataAndTransactions)
+		return
+	}
+
+	input := addblock.Input{
+		Data:        payload.Data,
+		Miner:       payload.Miner,
+		FromMempool: payload.FromMempool,
+	}
+	if payload.ParentHash != "" {
+		parent, err := domain.ParseHash(payload.ParentHash)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		input.ParentHash = parent
+	}
+	for _, tx := range payload.Transactions {
+		if tx.Payload == "" {
+			httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
+			return
+		}
+		input.Transactions = append(input.Transactions, addblock.TransactionInput{
+			Payload:   tx.Payload,
+			Timestamp: tx.Timestamp,
+		})
+	}
+
+	if payload.HashImpl != "" {
+		impl, err := domain.ParseHashImpl(payload.HashImpl)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		input.HashImpl = impl
+	}
+
+	if payload.Yield != "" {
+		yield, err := domain.ParseYieldStrategy(payload.Yield)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		input.Yield = yield
+	}
+	if payload.YieldInterval < 0 {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+	input.YieldInterval = payload.YieldInterval
+	input.Probe = payload.Probe
+
+	if payload.TimeoutMs < 0 {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+
+	ctx := r.Context()
+	if payload.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(payload.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	result, err := h.useCase.Execute(ctx, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
 	httpjson.WriteJSON(w, http.StatusCreated, result)
 }
+
+// writeError reports rejected transactions with their structured details
+func writeError(w http.ResponseWriter, err error) {
+	var txErr *domain.TransactionError
+	if errors.As(err, &txErr) {
+		httpjson.WriteErrorDetails(w, http.StatusBadRequest, err, txErr)
+		return
+	}
+	httpjson.WriteError(w, errorStatus(err), err)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidTransaction), errors.Is(err, domain.ErrInvalidAddress), errors.Is(err, domain.ErrInvalidHashImpl),
+		errors.Is(err, domain.ErrInvalidYieldStrategy):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrNothingToMine):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrUnknownParent):
+		return http.StatusUnprocessableEntity
+	case domain.IsMiningTimeout(err):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, httpjson.ErrServerShutdown):
+		return http.StatusServiceUnavailable
+	case domain.IsMiningCanceled(err):
+		return httpjson.StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
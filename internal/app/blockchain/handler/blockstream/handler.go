@@ -0,0 +1,106 @@
+package blockstream
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/blockstream"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	Path = "/blocks/stream"
+
+	// eventDropped is sent before the stream is closed for falling behind
+	eventDropped = "dropped"
+
+	heartbeatInterval = 15 * time.Second
+)
+
+type Handler struct {
+	useCase blockstream.UseCase
+}
+
+func NewHandler(useCase blockstream.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+// Handle pushes chain events as server-sent events until the client goes
+// away. A client that cannot keep up receives a dropped event and the
+// stream ends; it should reconnect and catch up through GET /blocks.
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	types, err := parseTypes(r.URL.Query().Get("events"))
+	if err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sub := h.useCase.Execute(r.Context(), blockstream.Input{Types: types, Buffer: domain.DefaultSubscriberBuffer})
+	defer sub.Close()
+
+	stream, err := httpjson.NewEventStream(w)
+	if err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	// Write errors mean the client went away; there is nobody to report them to
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if err := stream.Comment("heartbeat"); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Dropped() {
+					_ = stream.Send("", eventDropped, map[string]string{"reason": "subscriber fell behind"})
+				}
+				return
+			}
+			if err := stream.Send(strconv.FormatUint(event.ID, 10), string(event.Type), eventData(event)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventData is the payload of the event without its envelope, which SSE
+// already carries in the id and event fields
+func eventData(event domain.Event) interface{} {
+	if event.Block != nil {
+		return event.Block
+	}
+	return event.Progress
+}
+
+// parseTypes reads a comma-separated list of event types. An empty list
+// selects every type.
+func parseTypes(raw string) ([]domain.EventType, error) {
+	var types []domain.EventType
+	for name := range strings.SplitSeq(raw, ",") {
+		switch eventType := domain.EventType(strings.TrimSpace(name)); eventType {
+		case "":
+		case domain.EventBlock, domain.EventProgress:
+			types = append(types, eventType)
+		default:
+			return nil, fmt.Errorf("%w: unknown event type %q (expected %s or %s)",
+				httpjson.ErrInvalidValue, eventType, domain.EventBlock, domain.EventProgress)
+		}
+	}
+	return types, nil
+}
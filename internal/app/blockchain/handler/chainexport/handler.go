@@ -0,0 +1,34 @@
+package chainexport
+
+import (
+	"fmt"
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/usecase/chainexport"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/chain/export"
+
+type Handler struct {
+	useCase chainexport.UseCase
+}
+
+func NewHandler(useCase chainexport.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	export := h.useCase.Execute(r.Context())
+
+	// Browsers and curl -OJ save the export under a name telling its height
+	filename := fmt.Sprintf("chain-%d.json", export.Length-1)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	httpjson.WriteJSON(w, http.StatusOK, export)
+}
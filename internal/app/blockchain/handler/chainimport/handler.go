@@ -0,0 +1,80 @@
+package chainimport
+
+import (
+	"errors"
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/chainimport"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/chain/import"
+
+type Handler struct {
+	useCase chainimport.UseCase
+}
+
+func NewHandler(useCase chainimport.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+// Handle takes the file written by GET /chain/export as the body and the
+// mode as a query parameter: ?mode=replace or ?mode=append (default)
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	mode := domain.ImportAppend
+	if value := r.URL.Query().Get("mode"); value != "" {
+		parsed, err := domain.ParseImportMode(value)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		mode = parsed
+	}
+
+	var export domain.ChainExport
+	if err := httpjson.ReadJSON(r, &export); err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.useCase.Execute(r.Context(), chainimport.Input{
+		Export: export,
+		Mode:   mode,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, result)
+}
+
+// writeError reports validation failures with the report of every block
+func writeError(w http.ResponseWriter, err error) {
+	var importErr *domain.ImportError
+	if errors.As(err, &importErr) {
+		httpjson.WriteErrorDetails(w, http.StatusUnprocessableEntity, err, importErr.Report)
+		return
+	}
+	httpjson.WriteError(w, errorStatus(err), err)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidImportMode):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrIncompatibleExport), errors.Is(err, domain.ErrInvalidImport):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrImportNotExtending):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,29 @@
+package chaininfo
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/usecase/chaininfo"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/chain"
+
+type Handler struct {
+	useCase chaininfo.UseCase
+}
+
+func NewHandler(useCase chaininfo.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	info := h.useCase.Execute(r.Context())
+	httpjson.WriteJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,29 @@
+package chaintips
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/usecase/chaintips"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/chain/tips"
+
+type Handler struct {
+	useCase chaintips.UseCase
+}
+
+func NewHandler(useCase chaintips.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	tips := h.useCase.Execute(r.Context())
+	httpjson.WriteJSON(w, http.StatusOK, tips)
+}
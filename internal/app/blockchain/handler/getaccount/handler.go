@@ -0,0 +1,41 @@
+package getaccount
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/getaccount"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/accounts/{address}"
+
+type Handler struct {
+	useCase getaccount.UseCase
+}
+
+func NewHandler(useCase getaccount.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	address := mux.Vars(r)["address"]
+	if !domain.IsAddress(address) {
+		httpjson.WriteError(w, http.StatusBadRequest, domain.ErrInvalidAddress)
+		return
+	}
+
+	account, err := h.useCase.Execute(r.Context(), address)
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, account)
+}
@@ -0,0 +1,52 @@
+package getblock
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/getblock"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/blocks/{index:[0-9]+}"
+
+type Handler struct {
+	useCase getblock.UseCase
+}
+
+func NewHandler(useCase getblock.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	index, err := strconv.Atoi(mux.Vars(r)["index"])
+	if err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+
+	block, err := h.useCase.Execute(r.Context(), index)
+	if err != nil {
+		httpjson.WriteError(w, errorStatus(err), err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, block)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrBlockNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,51 @@
+package getblockbyhash
+
+import (
+	"errors"
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/getblockbyhash"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/blocks/hash/{hash:[0-9a-f]{64}}"
+
+type Handler struct {
+	useCase getblockbyhash.UseCase
+}
+
+func NewHandler(useCase getblockbyhash.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	hash, err := domain.ParseHash(mux.Vars(r)["hash"])
+	if err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	block, err := h.useCase.Execute(r.Context(), hash)
+	if err != nil {
+		httpjson.WriteError(w, errorStatus(err), err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, block)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrBlockNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
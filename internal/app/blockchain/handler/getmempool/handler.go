@@ -0,0 +1,29 @@
+package getmempool
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/usecase/getmempool"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/mempool"
+
+type Handler struct {
+	useCase getmempool.UseCase
+}
+
+func NewHandler(useCase getmempool.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	info := h.useCase.Execute(r.Context())
+	httpjson.WriteJSON(w, http.StatusOK, info)
+}
@@ -1,29 +1,116 @@
 package listblocks
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
 	"go-runtime-demo/internal/app/blockchain/usecase/listblocks"
+	"go-runtime-demo/internal/app/blockchain/usecase/streamblocks"
 	httpjson "go-runtime-demo/pkg/http"
 
 	"github.com/gorilla/mux"
 )
 
-const Path = "/blocks"
+const (
+	Path = "/blocks"
+
+	defaultLimit = 100
+	maxLimit     = 1000
+)
 
 type Handler struct {
-	useCase listblocks.UseCase
+	useCase       listblocks.UseCase
+	streamUseCase streamblocks.UseCase
 }
 
-func NewHandler(useCase listblocks.UseCase) Handler {
-	return Handler{useCase: useCase}
+func NewHandler(useCase listblocks.UseCase, streamUseCase streamblocks.UseCase) Handler {
+	return Handler{useCase: useCase, streamUseCase: streamUseCase}
 }
 
 func RegisterEndpoint(r *mux.Router, h Handler) {
 	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
 }
 
+// Handle returns the whole chain, or one page of it when from or limit is
+// given. Pages link to their neighbours in the Link header. Clients that
+// accept application/x-ndjson get the blocks streamed one per line instead
+// of a JSON array built from a copy of the chain.
 func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
-	blocks := h.useCase.Execute(r.Context())
-	httpjson.WriteJSON(w, http.StatusOK, blocks)
+	query := r.URL.Query()
+	paged := query.Has("from") || query.Has("limit")
+
+	var input listblocks.Input
+	if paged {
+		input.Limit = defaultLimit
+		if err := parseInt(query, "from", &input.From, 0); err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := parseInt(query, "limit", &input.Limit, 1); err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		input.Limit = min(input.Limit, maxLimit)
+	}
+
+	if httpjson.Accepts(r, httpjson.ContentTypeNDJSON) {
+		h.stream(w, r, input, paged)
+		return
+	}
+
+	result := h.useCase.Execute(r.Context(), input)
+	if paged {
+		setPageHeaders(w, input, result.Length)
+	}
+	httpjson.WriteJSON(w, http.StatusOK, result.Blocks)
+}
+
+func (h Handler) stream(w http.ResponseWriter, r *http.Request, input listblocks.Input, paged bool) {
+	result := h.streamUseCase.Execute(r.Context(), streamblocks.Input{From: input.From, Limit: input.Limit})
+	if paged {
+		setPageHeaders(w, input, result.Length)
+	}
+	// A write error means the client went away mid-stream; there is nobody to report it to
+	_ = httpjson.WriteNDJSON(w, http.StatusOK, result.Blocks)
+}
+
+func setPageHeaders(w http.ResponseWriter, input listblocks.Input, length int) {
+	w.Header().Set("Link", pageLinks(input, length))
+	w.Header().Set("X-Total-Count", strconv.Itoa(length))
+}
+
+// parseInt reads an optional query parameter of at least minimum
+func parseInt(query url.Values, name string, value *int, minimum int) error {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < minimum {
+		return fmt.Errorf("%w: %s must be an integer of at least %d", httpjson.ErrInvalidValue, name, minimum)
+	}
+	*value = n
+	return nil
+}
+
+// pageLinks builds an RFC 8288 Link header with the first, previous, next
+// and last pages of a chain of length blocks
+func pageLinks(input listblocks.Input, length int) string {
+	link := func(from int, rel string) string {
+		return fmt.Sprintf(`<%s?from=%d&limit=%d>; rel="%s"`, Path, from, input.Limit, rel)
+	}
+
+	last := max((length-1)/input.Limit*input.Limit, 0)
+	links := []string{link(0, "first")}
+	if input.From > 0 {
+		links = append(links, link(max(input.From-input.Limit, 0), "prev"))
+	}
+	if input.From+input.Limit < length {
+		links = append(links, link(input.From+input.Limit, "next"))
+	}
+	links = append(links, link(last, "last"))
+	return strings.Join(links, ", ")
 }
@@ -1,6 +1,13 @@
 package mineparallel
 
 type InputPayload struct {
-	Data       string `json:"data"`
-	Goroutines int    `json:"goroutines"`
+	Data          string `json:"data"`
+	Goroutines    int    `json:"goroutines"`
+	Strategy      string `json:"strategy"`       // "serialized", "split-nonce", "optimistic" (default: "serialized")
+	FromMempool   bool   `json:"from_mempool"`   // fill blocks with pending transactions, data becomes optional
+	TimeoutMs     int    `json:"timeout_ms"`     // optional deadline for the whole run in milliseconds
+	HashImpl      string `json:"hash_impl"`      // "naive" or "optimized" (default: "optimized")
+	Yield         string `json:"yield"`          // "none", "gosched", "sleep" or "lock-os-thread" (default: "gosched")
+	YieldInterval int    `json:"yield_interval"` // nonces between yields (default: 100000)
+	Probe         bool   `json:"probe"`          // measure scheduling delays with a probe goroutine
 }
@@ -1,8 +1,12 @@
 package mineparallel
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
+	"go-runtime-demo/internal/app/blockchain/domain"
 	"go-runtime-demo/internal/app/blockchain/usecase/mineparallel"
 	httpjson "go-runtime-demo/pkg/http"
 
@@ -30,7 +34,7 @@ func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if payload.Data == "" {
+	if payload.Data == "" && !payload.FromMempool {
 		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
 		return
 	}
@@ -39,6 +43,83 @@ func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		payload.Goroutines = 1
 	}
 
-	result := h.useCase.Execute(r.Context(), payload.Data, payload.Goroutines)
+	strategy := mineparallel.StrategySerialized
+	if payload.Strategy != "" {
+		parsed, err := mineparallel.ParseStrategy(payload.Strategy)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		strategy = parsed
+	}
+
+	var hashImpl domain.HashImpl
+	if payload.HashImpl != "" {
+		impl, err := domain.ParseHashImpl(payload.HashImpl)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		hashImpl = impl
+	}
+
+	var yield domain.YieldStrategy
+	if payload.Yield != "" {
+		parsed, err := domain.ParseYieldStrategy(payload.Yield)
+		if err != nil {
+			httpjson.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+		yield = parsed
+	}
+	if payload.YieldInterval < 0 {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+
+	if payload.TimeoutMs < 0 {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+
+	ctx := r.Context()
+	if payload.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(payload.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+
+	result, err := h.useCase.Execute(ctx, mineparallel.Input{
+		Data:          payload.Data,
+		Goroutines:    payload.Goroutines,
+		Strategy:      strategy,
+		FromMempool:   payload.FromMempool,
+		HashImpl:      hashImpl,
+		Yield:         yield,
+		YieldInterval: payload.YieldInterval,
+		Probe:         payload.Probe,
+	})
+	if err != nil {
+		httpjson.WriteError(w, errorStatus(err), err)
+		return
+	}
+
 	httpjson.WriteJSON(w, http.StatusOK, result)
 }
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, mineparallel.ErrInvalidStrategy), errors.Is(err, domain.ErrInvalidHashImpl), errors.Is(err, domain.ErrInvalidYieldStrategy):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrNothingToMine):
+		return http.StatusConflict
+	case domain.IsMiningTimeout(err):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, httpjson.ErrServerShutdown):
+		return http.StatusServiceUnavailable
+	case domain.IsMiningCanceled(err):
+		return httpjson.StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,68 @@
+package submitblock
+
+import (
+	"errors"
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/submitblock"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/blocks/submit"
+
+type Handler struct {
+	useCase submitblock.UseCase
+}
+
+func NewHandler(useCase submitblock.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	var block domain.Block
+	if err := httpjson.ReadJSON(r, &block); err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if block.Hash.IsZero() || block.PreviousHash.IsZero() {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
+		return
+	}
+
+	result, err := h.useCase.Execute(r.Context(), block)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusCreated, result)
+}
+
+// writeError reports validation failures with their structured details
+func writeError(w http.ResponseWriter, err error) {
+	var blockErr *domain.InvalidBlockError
+	if errors.As(err, &blockErr) {
+		httpjson.WriteErrorDetails(w, http.StatusBadRequest, err, blockErr.Errors)
+		return
+	}
+	httpjson.WriteError(w, errorStatus(err), err)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrBlockExists):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrUnknownParent):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,17 @@
+package submittransaction
+
+import "time"
+
+// InputPayload is a transaction signed by the client, e.g. through POST /wallets/{address}/sign
+type InputPayload struct {
+	ID        string    `json:"id"` // optional, checked against the content when present
+	Payload   string    `json:"payload"`
+	Timestamp time.Time `json:"timestamp"`
+	From      string    `json:"from"`
+	PublicKey string    `json:"public_key"`
+	Signature string    `json:"signature"`
+	To        string    `json:"to"`     // transfers only
+	Amount    uint64    `json:"amount"` // transfers only
+	Nonce     uint64    `json:"nonce"`  // transfers only, the sender's next nonce
+	Fee       uint64    `json:"fee"`    // transfers only, paid to the miner
+}
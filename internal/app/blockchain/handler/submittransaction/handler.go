@@ -0,0 +1,81 @@
+package submittransaction
+
+import (
+	"errors"
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/submittransaction"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/transactions"
+
+type Handler struct {
+	useCase submittransaction.UseCase
+}
+
+func NewHandler(useCase submittransaction.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	var payload InputPayload
+	if err := httpjson.ReadJSON(r, &payload); err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if payload.Payload == "" || payload.Timestamp.IsZero() {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
+		return
+	}
+
+	result, err := h.useCase.Execute(r.Context(), submittransaction.Input{
+		ID:        payload.ID,
+		Payload:   payload.Payload,
+		Timestamp: payload.Timestamp,
+		From:      payload.From,
+		PublicKey: payload.PublicKey,
+		Signature: payload.Signature,
+		To:        payload.To,
+		Amount:    payload.Amount,
+		Nonce:     payload.Nonce,
+		Fee:       payload.Fee,
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusAccepted, result)
+}
+
+// writeError reports rejected transactions with their structured details
+func writeError(w http.ResponseWriter, err error) {
+	var txErr *domain.TransactionError
+	if errors.As(err, &txErr) {
+		httpjson.WriteErrorDetails(w, http.StatusBadRequest, err, txErr)
+		return
+	}
+	httpjson.WriteError(w, errorStatus(err), err)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidTransaction):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrAlreadyPending):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrMempoolFull), errors.Is(err, domain.ErrMempoolDisabled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
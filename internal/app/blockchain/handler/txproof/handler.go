@@ -0,0 +1,54 @@
+package txproof
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/blockchain/usecase/txproof"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/blocks/{index:[0-9]+}/transactions/{txid}/proof"
+
+type Handler struct {
+	useCase txproof.UseCase
+}
+
+func NewHandler(useCase txproof.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	index, err := strconv.Atoi(vars["index"])
+	if err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+
+	result, err := h.useCase.Execute(r.Context(), index, vars["txid"])
+	if err != nil {
+		httpjson.WriteError(w, errorStatus(err), err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, result)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrBlockNotFound), errors.Is(err, domain.ErrTransactionNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
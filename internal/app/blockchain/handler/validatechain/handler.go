@@ -0,0 +1,29 @@
+package validatechain
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/blockchain/usecase/validatechain"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/blocks/validate"
+
+type Handler struct {
+	useCase validatechain.UseCase
+}
+
+func NewHandler(useCase validatechain.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	result := h.useCase.Execute(r.Context())
+	httpjson.WriteJSON(w, http.StatusOK, result)
+}
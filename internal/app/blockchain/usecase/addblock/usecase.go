@@ -13,14 +13,48 @@ type (
 		blockchain *domain.Blockchain
 	}
 
+	// Input carries either the legacy data string or a list of transactions.
+	// FromMempool appends the best pending transactions. ParentHash mines on
+	// that block instead of the tip, creating a fork. HashImpl selects the
+	// naive or optimized nonce search, Yield how often and how it yields.
+	// Probe measures how late a sleeping goroutine wakes up while mining.
+	Input struct {
+		Data          string               `json:"data"`
+		Transactions  []TransactionInput   `json:"transactions"`
+		Miner         string               `json:"miner"`
+		FromMempool   bool                 `json:"from_mempool"`
+		ParentHash    domain.Hash          `json:"parent_hash"`
+		HashImpl      domain.HashImpl      `json:"hash_impl"`
+		Yield         domain.YieldStrategy `json:"yield"`
+		YieldInterval int                  `json:"yield_interval"`
+		Probe         bool                 `json:"probe"`
+	}
+
+	TransactionInput struct {
+		Payload   string    `json:"payload"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+
 	Result struct {
-		Block         domain.Block `json:"block"`
-		Duration      string       `json:"duration"`
-		GCRuns        uint32       `json:"gc_runs"`
-		GCPauseMs     float64      `json:"gc_pause_ms"`
-		HeapDeltaMB   float64      `json:"heap_delta_mb"`
-		HeapObjects   uint64       `json:"heap_objects"`
-		GCCPUFraction float64      `json:"gc_cpu_fraction"`
+		Block          domain.Block         `json:"block"`
+		Fork           *domain.ForkChoice   `json:"fork,omitempty"`
+		DifficultyMode string               `json:"difficulty_mode"`
+		HashAlgorithm  string               `json:"hash_algorithm"`
+		ExpectedHashes float64              `json:"expected_hashes"`
+		HashImpl       domain.HashImpl      `json:"hash_impl"`
+		Hashes         int                  `json:"hashes"`
+		Mining         domain.MiningStats   `json:"mining"`
+		Yield          domain.YieldStrategy `json:"yield"`
+		YieldInterval  int                  `json:"yield_interval"`
+		Probe          *domain.ProbeStats   `json:"probe,omitempty"`
+		AllocsPerHash  float64              `json:"allocs_per_hash"`
+		BytesPerHash   float64              `json:"bytes_per_hash"`
+		Duration       string               `json:"duration"`
+		GCRuns         uint32               `json:"gc_runs"`
+		GCPauseMs      float64              `json:"gc_pause_ms"`
+		HeapDeltaMB    float64              `json:"heap_delta_mb"`
+		HeapObjects    uint64               `json:"heap_objects"`
+		GCCPUFraction  float64              `json:"gc_cpu_fraction"`
 	}
 )
 
@@ -30,23 +64,94 @@ func New(blockchain *domain.Blockchain) UseCase {
 	}
 }
 
-func (uc UseCase) Execute(_ context.Context, data string) Result {
+func (uc UseCase) Execute(ctx context.Context, input Input) (Result, error) {
+	payload := domain.Payload{
+		Data:        input.Data,
+		Miner:       input.Miner,
+		FromMempool: input.FromMempool,
+		Mining: domain.MiningOptions{
+			HashImpl:      input.HashImpl,
+			Yield:         input.Yield,
+			YieldInterval: input.YieldInterval,
+		},
+	}
+	for _, tx := range input.Transactions {
+		if tx.Timestamp.IsZero() {
+			tx.Timestamp = time.Now()
+		}
+		payload.Transactions = append(payload.Transactions, domain.NewTransaction(tx.Payload, tx.Timestamp))
+	}
+
+	// The options the nonce search applies, reported with the results
+	options := payload.Mining.WithDefaults()
+
+	var (
+		block  domain.Block
+		fork   *domain.ForkChoice
+		mining domain.MiningStats
+		err    error
+	)
+
+	// The probe starts before and stops after the allocation snapshots, so
+	// only its allocation-free sampling loop runs inside the window
+	var probe *domain.SchedulingProbe
+	if input.Probe {
+		probe = domain.StartSchedulingProbe(domain.DefaultProbeInterval)
+	}
+
 	var memBefore, memAfter runtime.MemStats
 	runtime.ReadMemStats(&memBefore)
 
 	start := time.Now()
-	block := uc.blockchain.AddBlock(data)
+	if !input.ParentHash.IsZero() {
+		var choice domain.ForkChoice
+		block, choice, mining, err = uc.blockchain.MineOn(ctx, input.ParentHash, payload)
+		fork = &choice
+	} else {
+		block, mining, err = uc.blockchain.AddBlock(ctx, payload)
+	}
 	duration := time.Since(start)
 
 	runtime.ReadMemStats(&memAfter)
+	probeStats := stopProbe(probe)
+	if err != nil {
+		return Result{}, err
+	}
 
 	return Result{
-		Block:         block,
-		Duration:      duration.String(),
-		GCRuns:        memAfter.NumGC - memBefore.NumGC,
-		GCPauseMs:     float64(memAfter.PauseTotalNs-memBefore.PauseTotalNs) / 1e6,
-		HeapDeltaMB:   float64(int64(memAfter.HeapAlloc)-int64(memBefore.HeapAlloc)) / 1024 / 1024,
-		HeapObjects:   memAfter.HeapObjects,
-		GCCPUFraction: memAfter.GCCPUFraction,
+		Block:          block,
+		Fork:           fork,
+		DifficultyMode: string(uc.blockchain.Params().Mode),
+		HashAlgorithm:  string(uc.blockchain.Params().Hasher.Algorithm()),
+		ExpectedHashes: uc.blockchain.Params().Mode.ExpectedHashes(block.Difficulty),
+		HashImpl:       options.HashImpl,
+		Hashes:         mining.Hashes,
+		Mining:         mining,
+		Yield:          options.Yield,
+		YieldInterval:  options.YieldInterval,
+		Probe:          probeStats,
+		AllocsPerHash:  perHash(memAfter.Mallocs-memBefore.Mallocs, mining.Hashes),
+		BytesPerHash:   perHash(memAfter.TotalAlloc-memBefore.TotalAlloc, mining.Hashes),
+		Duration:       duration.String(),
+		GCRuns:         memAfter.NumGC - memBefore.NumGC,
+		GCPauseMs:      float64(memAfter.PauseTotalNs-memBefore.PauseTotalNs) / 1e6,
+		HeapDeltaMB:    float64(int64(memAfter.HeapAlloc)-int64(memBefore.HeapAlloc)) / 1024 / 1024,
+		HeapObjects:    memAfter.HeapObjects,
+		GCCPUFraction:  memAfter.GCCPUFraction,
+	}, nil
+}
+
+// stopProbe stops probe and returns its statistics, or nil when no probe was requested
+func stopProbe(probe *domain.SchedulingProbe) *domain.ProbeStats {
+	if probe == nil {
+		return nil
 	}
+	stats := probe.Stop()
+	return &stats
+}
+
+// perHash spreads a process-wide allocation count over the hashes computed.
+// Mining dominates the request, so the other goroutines barely move it.
+func perHash(total uint64, hashes int) float64 {
+	return float64(total) / float64(max(hashes, 1))
 }
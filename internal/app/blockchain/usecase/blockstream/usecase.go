@@ -0,0 +1,30 @@
+package blockstream
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	// Input selects the events to receive. No types selects every type.
+	Input struct {
+		Types  []domain.EventType
+		Buffer int
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+// Execute subscribes to the chain events. The caller must Close the subscription.
+func (uc UseCase) Execute(_ context.Context, input Input) *domain.Subscription {
+	return uc.blockchain.Events().Subscribe(input.Buffer, input.Types...)
+}
@@ -0,0 +1,45 @@
+package chainimport
+
+import (
+	"context"
+	"time"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	Input struct {
+		Export domain.ChainExport
+		Mode   domain.ImportMode
+	}
+
+	Result struct {
+		domain.ImportResult
+		Duration string `json:"duration"`
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+// Execute validates the exported chain and imports it, reporting how long
+// validation and the swap took together
+func (uc UseCase) Execute(_ context.Context, input Input) (Result, error) {
+	start := time.Now()
+	result, err := uc.blockchain.Import(input.Export, input.Mode)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		ImportResult: result,
+		Duration:     time.Since(start).String(),
+	}, nil
+}
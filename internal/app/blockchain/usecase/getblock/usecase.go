@@ -0,0 +1,22 @@
+package getblock
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type UseCase struct {
+	blockchain *domain.Blockchain
+}
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+// Execute returns the canonical block at index
+func (uc UseCase) Execute(_ context.Context, index int) (domain.Block, error) {
+	return uc.blockchain.BlockAt(index)
+}
@@ -0,0 +1,22 @@
+package getblockbyhash
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type UseCase struct {
+	blockchain *domain.Blockchain
+}
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+// Execute returns a known block, canonical or on a side branch
+func (uc UseCase) Execute(_ context.Context, hash domain.Hash) (domain.Block, error) {
+	return uc.blockchain.BlockByHash(hash)
+}
@@ -0,0 +1,21 @@
+package getmempool
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type UseCase struct {
+	blockchain *domain.Blockchain
+}
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+func (uc UseCase) Execute(_ context.Context) domain.MempoolInfo {
+	return uc.blockchain.Mempool()
+}
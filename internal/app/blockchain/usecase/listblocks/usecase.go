@@ -6,9 +6,23 @@ import (
 	"go-runtime-demo/internal/app/blockchain/domain"
 )
 
-type UseCase struct {
-	blockchain *domain.Blockchain
-}
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	// Input selects a page of the canonical chain. A zero Limit selects the whole chain.
+	Input struct {
+		From  int
+		Limit int
+	}
+
+	Result struct {
+		Blocks []domain.Block
+		// Length is the length of the chain the page was taken from
+		Length int
+	}
+)
 
 func New(blockchain *domain.Blockchain) UseCase {
 	return UseCase{
@@ -16,6 +30,12 @@ func New(blockchain *domain.Blockchain) UseCase {
 	}
 }
 
-func (uc UseCase) Execute(_ context.Context) []domain.Block {
-	return uc.blockchain.Chain()
+func (uc UseCase) Execute(_ context.Context, input Input) Result {
+	if input.Limit == 0 {
+		blocks := uc.blockchain.Chain()
+		return Result{Blocks: blocks, Length: len(blocks)}
+	}
+
+	blocks, length := uc.blockchain.BlockRange(input.From, input.Limit)
+	return Result{Blocks: blocks, Length: length}
 }
@@ -2,50 +2,221 @@ package mineparallel
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"runtime"
+	"time"
 
 	"go-runtime-demo/internal/app/blockchain/domain"
 )
 
+const (
+	// StrategySerialized starts one goroutine per block, each calling AddBlock.
+	// The chain lock serializes them, which shows lock convoying.
+	StrategySerialized Strategy = "serialized"
+	// StrategySplitNonce mines a single block with every goroutine searching
+	// its own slice of the nonce space
+	StrategySplitNonce Strategy = "split-nonce"
+	// StrategyOptimistic mines one block per goroutine outside the chain lock
+	// and commits with a compare-and-append against the tip, re-mining when
+	// another goroutine committed first
+	StrategyOptimistic Strategy = "optimistic"
+)
+
+var ErrInvalidStrategy = errors.New("invalid mining strategy")
+
 type (
 	UseCase struct {
 		blockchain *domain.Blockchain
 	}
 
+	// Strategy selects how goroutines share the mining work
+	Strategy string
+
+	// Input describes the blocks to mine. With FromMempool each block also
+	// takes the best pending transactions, and Data may be empty. Probe
+	// measures how late a sleeping goroutine wakes up while mining.
+	Input struct {
+		Data          string               `json:"data"`
+		Goroutines    int                  `json:"goroutines"`
+		Strategy      Strategy             `json:"strategy"`
+		FromMempool   bool                 `json:"from_mempool"`
+		HashImpl      domain.HashImpl      `json:"hash_impl"`
+		Yield         domain.YieldStrategy `json:"yield"`
+		YieldInterval int                  `json:"yield_interval"`
+		Probe         bool                 `json:"probe"`
+	}
+
+	// Result reports the telemetry of every mined block in Mining, one entry
+	// per goroutine for the serialized and optimistic strategies. Split-nonce
+	// mining has a single entry, broken down per worker in Workers.
 	Result struct {
-		Blocks        []domain.Block `json:"blocks"`
-		Duration      string         `json:"duration"`
-		Goroutines    int            `json:"goroutines"`
-		TotalBlocks   int            `json:"total_blocks"`
-		GCRuns        uint32         `json:"gc_runs"`
-		GCPauseMs     float64        `json:"gc_pause_ms"`
-		HeapDeltaMB   float64        `json:"heap_delta_mb"`
-		GCCPUFraction float64        `json:"gc_cpu_fraction"`
+		Blocks         []domain.Block       `json:"blocks"`
+		Strategy       Strategy             `json:"strategy"`
+		DifficultyMode string               `json:"difficulty_mode"`
+		HashAlgorithm  string               `json:"hash_algorithm"`
+		ExpectedHashes float64              `json:"expected_hashes"`
+		HashImpl       domain.HashImpl      `json:"hash_impl"`
+		Hashes         int                  `json:"hashes"`
+		HashRate       float64              `json:"hash_rate"`
+		Yields         int                  `json:"yields"`
+		LockWaitMs     float64              `json:"lock_wait_ms"`
+		AllocsPerHash  float64              `json:"allocs_per_hash"`
+		BytesPerHash   float64              `json:"bytes_per_hash"`
+		Mining         []domain.MiningStats `json:"mining"`
+		Yield          domain.YieldStrategy `json:"yield"`
+		YieldInterval  int                  `json:"yield_interval"`
+		Probe          *domain.ProbeStats   `json:"probe,omitempty"`
+		Workers        []domain.WorkerStats `json:"workers,omitempty"`
+		Commits        []domain.CommitStats `json:"commits,omitempty"`
+		Duration       string               `json:"duration"`
+		Goroutines     int                  `json:"goroutines"`
+		TotalBlocks    int                  `json:"total_blocks"`
+		GCRuns         uint32               `json:"gc_runs"`
+		GCPauseMs      float64              `json:"gc_pause_ms"`
+		HeapDeltaMB    float64              `json:"heap_delta_mb"`
+		GCCPUFraction  float64              `json:"gc_cpu_fraction"`
 	}
 )
 
+// Strategies lists the supported strategies
+func Strategies() []Strategy {
+	return []Strategy{StrategySerialized, StrategySplitNonce, StrategyOptimistic}
+}
+
+func ParseStrategy(value string) (Strategy, error) {
+	switch strategy := Strategy(value); strategy {
+	case StrategySerialized, StrategySplitNonce, StrategyOptimistic:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected one of %v)", ErrInvalidStrategy, value, Strategies())
+	}
+}
+
 func New(blockchain *domain.Blockchain) UseCase {
 	return UseCase{
 		blockchain: blockchain,
 	}
 }
 
-func (uc UseCase) Execute(_ context.Context, data string, numGoroutines int) Result {
+func (uc UseCase) Execute(ctx context.Context, input Input) (Result, error) {
+	payload := domain.Payload{
+		Data:        input.Data,
+		FromMempool: input.FromMempool,
+		Mining: domain.MiningOptions{
+			HashImpl:      input.HashImpl,
+			Yield:         input.Yield,
+			YieldInterval: input.YieldInterval,
+		},
+	}
+	numGoroutines, strategy := input.Goroutines, input.Strategy
+
+	// The options the nonce search applies, reported with the results
+	options := payload.Mining.WithDefaults()
+
+	var (
+		blocks   []domain.Block
+		mining   []domain.MiningStats
+		workers  []domain.WorkerStats
+		commits  []domain.CommitStats
+		duration time.Duration
+		err      error
+	)
+
+	// The probe starts before and stops after the allocation snapshots, so
+	// only its allocation-free sampling loop runs inside the window
+	var probe *domain.SchedulingProbe
+	if input.Probe {
+		probe = domain.StartSchedulingProbe(domain.DefaultProbeInterval)
+	}
+
 	var memBefore, memAfter runtime.MemStats
 	runtime.ReadMemStats(&memBefore)
 
-	blocks, duration := uc.blockchain.MineParallel(data, numGoroutines)
-
+	switch strategy {
+	case StrategySplitNonce:
+		start := time.Now()
+		var (
+			block domain.Block
+			stats domain.MiningStats
+		)
+		block, stats, workers, err = uc.blockchain.MineSplitNonce(ctx, payload, numGoroutines)
+		duration = time.Since(start)
+		blocks = []domain.Block{block}
+		mining = []domain.MiningStats{stats}
+	case StrategyOptimistic:
+		blocks, commits, duration, err = uc.blockchain.MineParallelOptimistic(ctx, payload, numGoroutines)
+		for _, c := range commits {
+			mining = append(mining, c.MiningStats)
+		}
+	default:
+		strategy = StrategySerialized
+		blocks, mining, duration, err = uc.blockchain.MineParallel(ctx, payload, numGoroutines)
+	}
 	runtime.ReadMemStats(&memAfter)
+	probeStats := stopProbe(probe)
+	if err != nil {
+		return Result{}, err
+	}
+
+	mode := uc.blockchain.Params().Mode
+	var expectedHashes float64
+	for _, block := range blocks {
+		expectedHashes += mode.ExpectedHashes(block.Difficulty)
+	}
+	total := sumStats(mining)
 
 	return Result{
-		Blocks:        blocks,
-		Duration:      duration.String(),
-		Goroutines:    numGoroutines,
-		TotalBlocks:   uc.blockchain.Length(),
-		GCRuns:        memAfter.NumGC - memBefore.NumGC,
-		GCPauseMs:     float64(memAfter.PauseTotalNs-memBefore.PauseTotalNs) / 1e6,
-		HeapDeltaMB:   float64(int64(memAfter.HeapAlloc)-int64(memBefore.HeapAlloc)) / 1024 / 1024,
-		GCCPUFraction: memAfter.GCCPUFraction,
+		Blocks:         blocks,
+		Strategy:       strategy,
+		DifficultyMode: string(mode),
+		HashAlgorithm:  string(uc.blockchain.Params().Hasher.Algorithm()),
+		ExpectedHashes: expectedHashes,
+		HashImpl:       options.HashImpl,
+		Hashes:         total.Hashes,
+		HashRate:       domain.HashRate(total.Hashes, float64(duration.Nanoseconds())/1e6),
+		Yields:         total.Yields,
+		LockWaitMs:     total.LockWaitMs,
+		AllocsPerHash:  perHash(memAfter.Mallocs-memBefore.Mallocs, total.Hashes),
+		BytesPerHash:   perHash(memAfter.TotalAlloc-memBefore.TotalAlloc, total.Hashes),
+		Mining:         mining,
+		Yield:          options.Yield,
+		YieldInterval:  options.YieldInterval,
+		Probe:          probeStats,
+		Workers:        workers,
+		Commits:        commits,
+		Duration:       duration.String(),
+		Goroutines:     numGoroutines,
+		TotalBlocks:    uc.blockchain.Length(),
+		GCRuns:         memAfter.NumGC - memBefore.NumGC,
+		GCPauseMs:      float64(memAfter.PauseTotalNs-memBefore.PauseTotalNs) / 1e6,
+		HeapDeltaMB:    float64(int64(memAfter.HeapAlloc)-int64(memBefore.HeapAlloc)) / 1024 / 1024,
+		GCCPUFraction:  memAfter.GCCPUFraction,
+	}, nil
+}
+
+// sumStats adds up the hashes, yields and lock waits of every mined block.
+// Durations overlap between goroutines, so they are not added.
+func sumStats(mining []domain.MiningStats) domain.MiningStats {
+	var total domain.MiningStats
+	for _, m := range mining {
+		total.Hashes += m.Hashes
+		total.Yields += m.Yields
+		total.LockWaitMs += m.LockWaitMs
 	}
+	return total
+}
+
+// stopProbe stops probe and returns its statistics, or nil when no probe was requested
+func stopProbe(probe *domain.SchedulingProbe) *domain.ProbeStats {
+	if probe == nil {
+		return nil
+	}
+	stats := probe.Stop()
+	return &stats
+}
+
+// perHash spreads a process-wide allocation count over the hashes computed
+func perHash(total uint64, hashes int) float64 {
+	return float64(total) / float64(max(hashes, 1))
 }
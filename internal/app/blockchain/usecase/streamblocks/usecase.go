@@ -0,0 +1,39 @@
+package streamblocks
+
+import (
+	"context"
+	"iter"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	// Input selects a range of the canonical chain. A zero Limit selects every block from From.
+	Input struct {
+		From  int
+		Limit int
+	}
+
+	// Result iterates over the blocks of a snapshot of the chain, which is
+	// never copied as a whole
+	Result struct {
+		Blocks iter.Seq[domain.Block]
+		// Length is the length of the snapshot
+		Length int
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+func (uc UseCase) Execute(_ context.Context, input Input) Result {
+	blocks, length := uc.blockchain.Blocks(input.From, input.Limit)
+	return Result{Blocks: blocks, Length: length}
+}
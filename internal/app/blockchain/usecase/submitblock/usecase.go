@@ -0,0 +1,37 @@
+package submitblock
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	Result struct {
+		Block domain.Block `json:"block"`
+		domain.ForkChoice
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+// Execute adds a block mined elsewhere and reports the resulting fork choice
+func (uc UseCase) Execute(_ context.Context, block domain.Block) (Result, error) {
+	choice, err := uc.blockchain.SubmitBlock(block)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Block:      block,
+		ForkChoice: choice,
+	}, nil
+}
@@ -0,0 +1,72 @@
+package submittransaction
+
+import (
+	"context"
+	"time"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	// Input is a transaction signed by the client. ID is optional and checked
+	// against the content when present.
+	Input struct {
+		ID        string    `json:"id"`
+		Payload   string    `json:"payload"`
+		Timestamp time.Time `json:"timestamp"`
+		From      string    `json:"from"`
+		PublicKey string    `json:"public_key"`
+		Signature string    `json:"signature"`
+		To        string    `json:"to"`
+		Amount    uint64    `json:"amount"`
+		Nonce     uint64    `json:"nonce"`
+		Fee       uint64    `json:"fee"`
+	}
+
+	Result struct {
+		Transaction domain.Transaction `json:"transaction"`
+		Evicted     []string           `json:"evicted,omitempty"`
+		MempoolSize int                `json:"mempool_size"`
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+// Execute verifies the transaction and adds it to the mempool. Transfers the
+// sender cannot afford or that reuse a nonce, mined or pending, are rejected.
+func (uc UseCase) Execute(_ context.Context, input Input) (Result, error) {
+	tx := domain.Transaction{
+		ID:        input.ID,
+		Payload:   input.Payload,
+		Timestamp: input.Timestamp,
+		From:      input.From,
+		PublicKey: input.PublicKey,
+		Signature: input.Signature,
+		To:        input.To,
+		Amount:    input.Amount,
+		Nonce:     input.Nonce,
+		Fee:       input.Fee,
+	}
+	if tx.ID == "" {
+		tx.ID = tx.ComputeID()
+	}
+
+	evicted, err := uc.blockchain.SubmitTransaction(tx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Transaction: tx,
+		Evicted:     evicted,
+		MempoolSize: uc.blockchain.Mempool().Size,
+	}, nil
+}
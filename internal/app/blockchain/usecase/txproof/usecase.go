@@ -0,0 +1,44 @@
+package txproof
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	// Result holds everything a light client needs: the block header (without
+	// transactions) and the Merkle path of the transaction
+	Result struct {
+		Header domain.Block       `json:"header"`
+		Proof  domain.MerkleProof `json:"proof"`
+		// HashAlgorithm is the hasher the header hash must be recomputed with
+		HashAlgorithm domain.HashAlgorithm `json:"hash_algorithm"`
+		Verified      bool                 `json:"verified"`
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+func (uc UseCase) Execute(_ context.Context, index int, txID string) (Result, error) {
+	header, proof, err := uc.blockchain.TransactionProof(index, txID)
+	if err != nil {
+		return Result{}, err
+	}
+
+	hasher := uc.blockchain.Params().Hasher
+	return Result{
+		Header:        header,
+		Proof:         proof,
+		HashAlgorithm: hasher.Algorithm(),
+		Verified:      domain.VerifyMerkleProof(header, proof, hasher) == nil,
+	}, nil
+}
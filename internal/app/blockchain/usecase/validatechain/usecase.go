@@ -0,0 +1,35 @@
+package validatechain
+
+import (
+	"context"
+	"time"
+
+	"go-runtime-demo/internal/app/blockchain/domain"
+)
+
+type (
+	UseCase struct {
+		blockchain *domain.Blockchain
+	}
+
+	Result struct {
+		domain.ValidationReport
+		Duration string `json:"duration"`
+	}
+)
+
+func New(blockchain *domain.Blockchain) UseCase {
+	return UseCase{
+		blockchain: blockchain,
+	}
+}
+
+func (uc UseCase) Execute(_ context.Context) Result {
+	start := time.Now()
+	report := uc.blockchain.Validate()
+
+	return Result{
+		ValidationReport: report,
+		Duration:         time.Since(start).String(),
+	}
+}
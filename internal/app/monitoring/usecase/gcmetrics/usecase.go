@@ -18,6 +18,14 @@ type (
 		GCHeapGoalBytes     uint64 `json:"/gc/heap/goal:bytes"`
 		GCHeapObjects       uint64 `json:"/gc/heap/objects:objects"`
 		GCHeapTinyAllocs    uint64 `json:"/gc/heap/tiny/allocs:objects"`
+		GCHeapLiveBytes     uint64 `json:"/gc/heap/live:bytes"`
+
+		// GC Scan Metrics: the bytes that may contain pointers, i.e. the
+		// marking work of the next cycle, as of the last cycle
+		GCScanHeapBytes    uint64 `json:"/gc/scan/heap:bytes"`
+		GCScanStackBytes   uint64 `json:"/gc/scan/stack:bytes"`
+		GCScanGlobalsBytes uint64 `json:"/gc/scan/globals:bytes"`
+		GCScanTotalBytes   uint64 `json:"/gc/scan/total:bytes"`
 
 		// GC Cycle Metrics
 		GCCyclesTotal  uint64 `json:"/gc/cycles/total:gc-cycles"`
@@ -56,6 +64,11 @@ func (uc UseCase) Execute(_ context.Context) MetricsResult {
 		{Name: "/gc/heap/goal:bytes"},
 		{Name: "/gc/heap/objects:objects"},
 		{Name: "/gc/heap/tiny/allocs:objects"},
+		{Name: "/gc/heap/live:bytes"},
+		{Name: "/gc/scan/heap:bytes"},
+		{Name: "/gc/scan/stack:bytes"},
+		{Name: "/gc/scan/globals:bytes"},
+		{Name: "/gc/scan/total:bytes"},
 		{Name: "/gc/cycles/total:gc-cycles"},
 		{Name: "/gc/cycles/forced-gc:gc-cycles"},
 		{Name: "/gc/pause/total:seconds"},
@@ -90,6 +103,16 @@ func (uc UseCase) Execute(_ context.Context) MetricsResult {
 			result.GCHeapObjects = s.Value.Uint64()
 		case "/gc/heap/tiny/allocs:objects":
 			result.GCHeapTinyAllocs = s.Value.Uint64()
+		case "/gc/heap/live:bytes":
+			result.GCHeapLiveBytes = s.Value.Uint64()
+		case "/gc/scan/heap:bytes":
+			result.GCScanHeapBytes = s.Value.Uint64()
+		case "/gc/scan/stack:bytes":
+			result.GCScanStackBytes = s.Value.Uint64()
+		case "/gc/scan/globals:bytes":
+			result.GCScanGlobalsBytes = s.Value.Uint64()
+		case "/gc/scan/total:bytes":
+			result.GCScanTotalBytes = s.Value.Uint64()
 		case "/gc/cycles/total:gc-cycles":
 			result.GCCyclesTotal = s.Value.Uint64()
 		case "/gc/cycles/forced-gc:gc-cycles":
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+)
+
+const (
+	StatusPath = "/p2p/status"
+	BlocksPath = "/p2p/blocks"
+)
+
+// fetchStatus asks peer for its status and checks that it shares our genesis
+func (n *Node) fetchStatus(ctx context.Context, peer string) (*Status, error) {
+	var status Status
+	if err := n.do(ctx, http.MethodGet, peer+StatusPath, nil, &status); err != nil {
+		return nil, err
+	}
+	if genesis := n.blockchain.Genesis().Hash; status.GenesisHash != genesis {
+		return nil, fmt.Errorf("%w: %s, ours is %s", ErrGenesisMismatch, status.GenesisHash, genesis)
+	}
+	return &status, nil
+}
+
+// fetchBlocks downloads a batch of the canonical chain of peer from index from
+func (n *Node) fetchBlocks(ctx context.Context, peer string, from int) ([]blockchaindomain.Block, error) {
+	query := url.Values{}
+	query.Set("from", strconv.Itoa(from))
+	query.Set("limit", strconv.Itoa(syncBatchSize))
+
+	var blocks []blockchaindomain.Block
+	if err := n.do(ctx, http.MethodGet, peer+BlocksPath+"?"+query.Encode(), nil, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+// announce sends a new block to peer
+func (n *Node) announce(ctx context.Context, peer string, block blockchaindomain.Block) (Receipt, error) {
+	body, err := json.Marshal(block)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	var receipt Receipt
+	if err := n.do(ctx, http.MethodPost, peer+BlocksPath, body, &receipt); err != nil {
+		return Receipt{}, err
+	}
+	return receipt, nil
+}
+
+// do sends a request identifying this node and decodes a 2xx JSON response into out
+func (n *Node) do(ctx context.Context, method, target string, body []byte, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(NodeHeader, n.self)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s: %s: %s", method, target, resp.Status, apiErr.Error)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
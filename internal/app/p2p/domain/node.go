@@ -0,0 +1,627 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+)
+
+const (
+	// NodeHeader carries the URL of the sending node on every request to a
+	// peer. It is how a node learns about the peers that contact it.
+	NodeHeader = "X-Node-URL"
+
+	DefaultSyncInterval = 5 * time.Second
+	// DefaultMaxPeers bounds the peer set, configured peers included
+	DefaultMaxPeers = 32
+	// MaxRangeLimit bounds the number of blocks served by one range request
+	MaxRangeLimit = 500
+
+	// ReceiptAccepted means the block was new and stored
+	ReceiptAccepted ReceiptStatus = "accepted"
+	// ReceiptKnown means the block was already stored
+	ReceiptKnown ReceiptStatus = "known"
+	// ReceiptSyncing means the parent is unknown and a sync from the sender was scheduled
+	ReceiptSyncing ReceiptStatus = "syncing"
+
+	syncBatchSize   = 100
+	gossipQueueSize = 256
+	requestTimeout  = 5 * time.Second
+	// maxPeerFailures is the number of consecutive failures after which a
+	// discovered peer is forgotten. Configured peers are kept.
+	maxPeerFailures = 5
+	// probeQueueSize bounds the candidate peers waiting for a status probe.
+	// Candidates beyond it are dropped and have to contact us again.
+	probeQueueSize = 16
+)
+
+var (
+	ErrInvalidPeerURL  = errors.New("invalid peer url")
+	ErrGenesisMismatch = errors.New("peer has a different genesis block")
+)
+
+type (
+	Config struct {
+		// URL is the address peers reach this node at
+		URL          string
+		Peers        []string
+		SyncInterval time.Duration
+		// MaxPeers bounds the number of peers, DefaultMaxPeers when zero
+		MaxPeers int
+	}
+
+	// Status is what a node tells its peers about itself
+	Status struct {
+		Node        string                `json:"node"`
+		GenesisHash blockchaindomain.Hash `json:"genesis_hash"`
+		Height      int                   `json:"height"`
+		TipHash     blockchaindomain.Hash `json:"tip_hash"`
+		TotalWork   float64               `json:"total_work"`
+		Peers       []PeerInfo            `json:"peers"`
+		Stats       Stats                 `json:"stats"`
+	}
+
+	// PeerInfo is the last known state of a peer
+	PeerInfo struct {
+		URL          string                `json:"url"`
+		Configured   bool                  `json:"configured"`
+		Height       int                   `json:"height"`
+		TipHash      blockchaindomain.Hash `json:"tip_hash,omitzero"`
+		TotalWork    float64               `json:"total_work"`
+		LastSeen     time.Time             `json:"last_seen,omitzero"`
+		Failures     int                   `json:"failures"`
+		LastError    string                `json:"last_error,omitempty"`
+		Incompatible bool                  `json:"incompatible,omitempty"`
+	}
+
+	Stats struct {
+		BlocksReceived int `json:"blocks_received"`
+		BlocksSynced   int `json:"blocks_synced"`
+		Announcements  int `json:"announcements"`
+		// GossipDropped counts blocks not announced because the gossip queue was full
+		GossipDropped int `json:"gossip_dropped"`
+		Syncs         int `json:"syncs"`
+		// PeersRejected counts candidate peers not added: invalid URL, peer
+		// set full, probe queue full or failed status probe
+		PeersRejected int `json:"peers_rejected"`
+	}
+
+	ReceiptStatus string
+
+	// Receipt is the answer to a gossiped block
+	Receipt struct {
+		Status ReceiptStatus                `json:"status"`
+		Fork   *blockchaindomain.ForkChoice `json:"fork,omitempty"`
+	}
+
+	// origin is where a block being added came from. Blocks fetched by a
+	// range sync are not relayed: peers that miss them sync on their own.
+	origin struct {
+		peer   string
+		synced bool
+	}
+
+	// Node connects the blockchain to its peers. New blocks are announced
+	// to every peer by a gossip goroutine, and a sync goroutine polls the
+	// peers and fetches the blocks of any branch with more work than ours.
+	// Peers we hear about are only added once a probe goroutine got their
+	// status, so a caller cannot fill the peer set with URLs nobody answers.
+	Node struct {
+		self         string
+		blockchain   *blockchaindomain.Blockchain
+		client       *http.Client
+		syncInterval time.Duration
+		maxPeers     int
+		peers        map[string]*PeerInfo
+		probing      map[string]bool
+		origins      map[blockchaindomain.Hash]origin
+		outbox       chan blockchaindomain.Block
+		probes       chan string
+		syncRequests chan string
+		stats        Stats
+		// mu is taken by the block listener under the chain lock, so it
+		// must never be held while calling into the blockchain
+		mu sync.Mutex
+	}
+)
+
+// NewNode registers the node as a block listener of blockchain. Nothing is
+// sent to peers until Run is called.
+func NewNode(blockchain *blockchaindomain.Blockchain, cfg Config) (*Node, error) {
+	self, err := normalizeURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		self:         self,
+		blockchain:   blockchain,
+		client:       &http.Client{Timeout: requestTimeout},
+		syncInterval: cfg.SyncInterval,
+		maxPeers:     cfg.MaxPeers,
+		peers:        make(map[string]*PeerInfo),
+		probing:      make(map[string]bool),
+		origins:      make(map[blockchaindomain.Hash]origin),
+		outbox:       make(chan blockchaindomain.Block, gossipQueueSize),
+		probes:       make(chan string, probeQueueSize),
+		syncRequests: make(chan string, 1),
+	}
+	if n.syncInterval <= 0 {
+		n.syncInterval = DefaultSyncInterval
+	}
+	if n.maxPeers <= 0 {
+		n.maxPeers = DefaultMaxPeers
+	}
+	if len(cfg.Peers) > n.maxPeers {
+		return nil, fmt.Errorf("%d peers configured, at most %d allowed", len(cfg.Peers), n.maxPeers)
+	}
+
+	for _, peer := range cfg.Peers {
+		peerURL, err := normalizeURL(peer)
+		if err != nil {
+			return nil, err
+		}
+		if peerURL != n.self {
+			n.peers[peerURL] = &PeerInfo{URL: peerURL, Configured: true}
+		}
+	}
+
+	blockchain.OnBlock(n.enqueue)
+	return n, nil
+}
+
+// normalizeURL accepts http(s) URLs made of a host and an optional path,
+// and strips trailing slashes so the same peer is always recorded under the
+// same key. Credentials, queries and fragments are refused: peer paths are
+// appended to the URL.
+func normalizeURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" ||
+		u.User != nil || u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidPeerURL, raw)
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// Run gossips, syncs and probes candidate peers until ctx is done. It first
+// catches up with the peers, so a node started empty downloads and validates
+// their chain.
+func (n *Node) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		n.gossip(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		n.syncLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		n.probeLoop(ctx)
+	}()
+	wg.Wait()
+}
+
+// Status describes the node and the peers it knows
+func (n *Node) Status() Status {
+	info := n.blockchain.Info()
+	genesis := n.blockchain.Genesis()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return Status{
+		Node:        n.self,
+		GenesisHash: genesis.Hash,
+		Height:      info.Height,
+		TipHash:     info.TipHash,
+		TotalWork:   info.TotalWork,
+		Peers:       n.peerList(),
+		Stats:       n.stats,
+	}
+}
+
+// Blocks returns up to limit canonical blocks from index from, for peers
+// syncing a range
+func (n *Node) Blocks(from, limit int) []blockchaindomain.Block {
+	blocks, _ := n.blockchain.BlockRange(from, min(limit, MaxRangeLimit))
+	return blocks
+}
+
+// Receive validates and stores a block announced by sender, which may be
+// empty when the sender did not identify itself. A block whose parent is
+// unknown schedules a sync from the sender instead of failing.
+func (n *Node) Receive(sender string, block blockchaindomain.Block) (Receipt, error) {
+	if sender != "" {
+		sender = n.Discover(sender)
+	}
+
+	n.mu.Lock()
+	n.stats.BlocksReceived++
+	n.origins[block.Hash] = origin{peer: sender}
+	n.mu.Unlock()
+
+	choice, err := n.blockchain.SubmitBlock(block)
+	if err != nil {
+		n.forgetOrigin(block.Hash)
+	}
+
+	switch {
+	case errors.Is(err, blockchaindomain.ErrBlockExists):
+		return Receipt{Status: ReceiptKnown}, nil
+	case errors.Is(err, blockchaindomain.ErrUnknownParent) && sender != "":
+		n.requestSync(sender)
+		return Receipt{Status: ReceiptSyncing}, nil
+	case err != nil:
+		return Receipt{}, err
+	}
+
+	return Receipt{Status: ReceiptAccepted, Fork: &choice}, nil
+}
+
+// Discover queues a status probe of a peer that contacted us or that a peer
+// told us about, and returns its normalized URL, or an empty string when the
+// URL is not usable. The peer is added once it answers the probe with our
+// genesis, provided the peer set is not full by then.
+func (n *Node) Discover(peer string) string {
+	peerURL, err := normalizeURL(peer)
+	if err != nil {
+		n.mu.Lock()
+		n.stats.PeersRejected++
+		n.mu.Unlock()
+		return ""
+	}
+	if peerURL == n.self {
+		return ""
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, ok := n.peers[peerURL]; ok || n.probing[peerURL] {
+		return peerURL
+	}
+	if len(n.peers)+len(n.probing) >= n.maxPeers {
+		n.stats.PeersRejected++
+		return peerURL
+	}
+
+	select {
+	case n.probes <- peerURL:
+		n.probing[peerURL] = true
+	default:
+		n.stats.PeersRejected++
+	}
+	return peerURL
+}
+
+// probeLoop fetches the status of each candidate peer and adds those that
+// answer. A peer with another genesis is added as incompatible, like a
+// configured one, so it is not probed again each time it is mentioned.
+func (n *Node) probeLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case peer := <-n.probes:
+			status, err := n.fetchStatus(ctx, peer)
+			if !n.addPeer(peer, status, err) {
+				continue
+			}
+			log.Printf("Discovered peer %s", peer)
+			if status != nil {
+				n.learnPeers(status)
+			}
+		}
+	}
+}
+
+// addPeer records peer after its status probe and reports whether it was added
+func (n *Node) addPeer(peer string, status *Status, err error) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.probing, peer)
+	if _, ok := n.peers[peer]; ok {
+		return false
+	}
+	incompatible := errors.Is(err, ErrGenesisMismatch)
+	if (err != nil && !incompatible) || len(n.peers) >= n.maxPeers {
+		n.stats.PeersRejected++
+		return false
+	}
+
+	info := &PeerInfo{URL: peer, LastSeen: time.Now(), Incompatible: incompatible}
+	if incompatible {
+		info.LastError = err.Error()
+	} else {
+		info.Height = status.Height
+		info.TipHash = status.TipHash
+		info.TotalWork = status.TotalWork
+	}
+	n.peers[peer] = info
+	return true
+}
+
+// learnPeers queues a probe of the peers listed by a status
+func (n *Node) learnPeers(status *Status) {
+	for _, p := range status.Peers {
+		n.Discover(p.URL)
+	}
+}
+
+// enqueue is the block listener. It runs with the chain lock held, so a
+// full queue drops the announcement rather than blocking the chain.
+func (n *Node) enqueue(block blockchaindomain.Block) {
+	select {
+	case n.outbox <- block:
+	default:
+		n.mu.Lock()
+		n.stats.GossipDropped++
+		delete(n.origins, block.Hash)
+		n.mu.Unlock()
+	}
+}
+
+// gossip announces new blocks to every peer concurrently. While the
+// requests wait on the network their goroutines are parked in the netpoller
+// and use no P.
+func (n *Node) gossip(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case block := <-n.outbox:
+			from := n.forgetOrigin(block.Hash)
+			if from.synced {
+				continue
+			}
+
+			var wg sync.WaitGroup
+			for _, peer := range n.peerURLs(true) {
+				if peer == from.peer {
+					continue
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					receipt, err := n.announce(ctx, peer, block)
+					n.recordResult(peer, nil, err)
+					if err == nil && receipt.Status == ReceiptAccepted {
+						n.mu.Lock()
+						n.stats.Announcements++
+						n.mu.Unlock()
+					}
+				}()
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// forgetOrigin removes and returns where block came from
+func (n *Node) forgetOrigin(hash blockchaindomain.Hash) origin {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	from := n.origins[hash]
+	delete(n.origins, hash)
+	return from
+}
+
+// requestSync asks the sync goroutine to sync from peer. A request already
+// pending is enough: the sync goroutine also polls every peer periodically.
+func (n *Node) requestSync(peer string) {
+	select {
+	case n.syncRequests <- peer:
+	default:
+	}
+}
+
+func (n *Node) syncLoop(ctx context.Context) {
+	ticker := time.NewTicker(n.syncInterval)
+	defer ticker.Stop()
+
+	n.syncAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.syncAll(ctx)
+		case peer := <-n.syncRequests:
+			status, err := n.fetchStatus(ctx, peer)
+			n.recordResult(peer, status, err)
+			if err == nil {
+				n.syncFrom(ctx, peer, *status)
+			}
+		}
+	}
+}
+
+// syncAll polls every peer concurrently and syncs from the one with the
+// most work when it has more than our tip
+func (n *Node) syncAll(ctx context.Context) {
+	peers := n.peerURLs(false)
+	statuses := make([]*Status, len(peers))
+
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, err := n.fetchStatus(ctx, peer)
+			n.recordResult(peer, status, err)
+			if err == nil {
+				statuses[i] = status
+			}
+		}()
+	}
+	wg.Wait()
+
+	best := -1
+	for i, status := range statuses {
+		if status != nil && (best < 0 || status.TotalWork > statuses[best].TotalWork) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		n.syncFrom(ctx, peers[best], *statuses[best])
+	}
+}
+
+// syncFrom downloads the canonical chain of peer when it has more work than
+// ours. Every block goes through SubmitBlock, so it is validated against
+// its branch and the fork choice decides whether it becomes canonical.
+func (n *Node) syncFrom(ctx context.Context, peer string, status Status) {
+	info := n.blockchain.Info()
+	if status.TotalWork <= info.TotalWork {
+		return
+	}
+
+	n.mu.Lock()
+	n.stats.Syncs++
+	n.mu.Unlock()
+
+	synced, err := n.fetchBranch(ctx, peer, min(info.Height, status.Height))
+	if synced > 0 {
+		log.Printf("Synced %d blocks from %s", synced, peer)
+	}
+	if err != nil {
+		log.Printf("Sync from %s: %v", peer, err)
+		n.recordResult(peer, nil, err)
+	}
+}
+
+// fetchBranch finds the last block we share with peer, stepping back
+// exponentially from height, then submits the rest of its chain in batches.
+// It returns the number of blocks added.
+func (n *Node) fetchBranch(ctx context.Context, peer string, height int) (int, error) {
+	from := max(height, 1)
+	step := 1
+	blocks, err := n.fetchBlocks(ctx, peer, from)
+	for err == nil && len(blocks) > 0 && !n.blockchain.HasBlock(blocks[0].PreviousHash) {
+		if from == 1 {
+			return 0, fmt.Errorf("%w: block 1 builds on %s", ErrGenesisMismatch, blocks[0].PreviousHash)
+		}
+		from = max(from-step, 1)
+		step *= 2
+		blocks, err = n.fetchBlocks(ctx, peer, from)
+	}
+
+	synced := 0
+	for err == nil && len(blocks) > 0 {
+		for _, block := range blocks {
+			added, err := n.submitSynced(peer, block)
+			if err != nil {
+				return synced, fmt.Errorf("block %d: %w", block.Index, err)
+			}
+			if added {
+				synced++
+			}
+		}
+		if len(blocks) < syncBatchSize {
+			break
+		}
+		blocks, err = n.fetchBlocks(ctx, peer, blocks[len(blocks)-1].Index+1)
+	}
+	return synced, err
+}
+
+// submitSynced adds a block fetched from peer and reports whether it was new
+func (n *Node) submitSynced(peer string, block blockchaindomain.Block) (bool, error) {
+	n.mu.Lock()
+	n.origins[block.Hash] = origin{peer: peer, synced: true}
+	n.mu.Unlock()
+
+	_, err := n.blockchain.SubmitBlock(block)
+	if err != nil {
+		n.forgetOrigin(block.Hash)
+	}
+	if errors.Is(err, blockchaindomain.ErrBlockExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	n.mu.Lock()
+	n.stats.BlocksSynced++
+	n.mu.Unlock()
+	return true, nil
+}
+
+// recordResult updates the state of peer after a request. A status also
+// teaches us the peers it knows. Discovered peers that keep failing are forgotten.
+func (n *Node) recordResult(peer string, status *Status, err error) {
+	n.mu.Lock()
+	info, ok := n.peers[peer]
+	if !ok {
+		n.mu.Unlock()
+		return
+	}
+	switch {
+	case err != nil:
+		info.Failures++
+		info.LastError = err.Error()
+		info.Incompatible = errors.Is(err, ErrGenesisMismatch)
+		if info.Failures >= maxPeerFailures && !info.Configured {
+			delete(n.peers, peer)
+			log.Printf("Forgot peer %s after %d failures: %v", peer, info.Failures, err)
+		}
+	case status != nil:
+		info.Height = status.Height
+		info.TipHash = status.TipHash
+		info.TotalWork = status.TotalWork
+		info.LastSeen = time.Now()
+		info.Failures = 0
+		info.LastError = ""
+		info.Incompatible = false
+	default:
+		info.LastSeen = time.Now()
+		info.Failures = 0
+		info.LastError = ""
+	}
+	n.mu.Unlock()
+
+	if status != nil {
+		n.learnPeers(status)
+	}
+}
+
+// peerURLs returns the sorted peer URLs. Incompatible peers are still polled,
+// in case they restart with our genesis, but are not sent blocks.
+func (n *Node) peerURLs(compatibleOnly bool) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	urls := make([]string, 0, len(n.peers))
+	for peerURL, info := range n.peers {
+		if !compatibleOnly || !info.Incompatible {
+			urls = append(urls, peerURL)
+		}
+	}
+	slices.Sort(urls)
+	return urls
+}
+
+// peerList returns a copy of the peer states sorted by URL. Callers must hold n.mu.
+func (n *Node) peerList() []PeerInfo {
+	peers := make([]PeerInfo, 0, len(n.peers))
+	for _, info := range n.peers {
+		peers = append(peers, *info)
+	}
+	slices.SortFunc(peers, func(a, b PeerInfo) int {
+		return strings.Compare(a.URL, b.URL)
+	})
+	return peers
+}
@@ -0,0 +1,55 @@
+package blockrange
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-runtime-demo/internal/app/p2p/domain"
+	"go-runtime-demo/internal/app/p2p/usecase/blockrange"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	Path = domain.BlocksPath
+
+	defaultLimit = 100
+)
+
+type Handler struct {
+	useCase blockrange.UseCase
+}
+
+func NewHandler(useCase blockrange.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil || from < 0 {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+		return
+	}
+
+	limit := defaultLimit
+	if raw := query.Get("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrInvalidValue)
+			return
+		}
+	}
+
+	blocks := h.useCase.Execute(r.Context(), blockrange.Input{
+		Caller: r.Header.Get(domain.NodeHeader),
+		From:   from,
+		Limit:  limit,
+	})
+	httpjson.WriteJSON(w, http.StatusOK, blocks)
+}
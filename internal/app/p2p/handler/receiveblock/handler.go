@@ -0,0 +1,71 @@
+package receiveblock
+
+import (
+	"errors"
+	"net/http"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/p2p/domain"
+	"go-runtime-demo/internal/app/p2p/usecase/receiveblock"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = domain.BlocksPath
+
+type Handler struct {
+	useCase receiveblock.UseCase
+}
+
+func NewHandler(useCase receiveblock.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	var block blockchaindomain.Block
+	if err := httpjson.ReadJSON(r, &block); err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if block.Hash.IsZero() || block.PreviousHash.IsZero() {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
+		return
+	}
+
+	receipt, err := h.useCase.Execute(r.Context(), r.Header.Get(domain.NodeHeader), block)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if receipt.Status != domain.ReceiptKnown {
+		status = http.StatusAccepted
+	}
+	httpjson.WriteJSON(w, status, receipt)
+}
+
+// writeError reports validation failures with their structured details
+func writeError(w http.ResponseWriter, err error) {
+	var blockErr *blockchaindomain.InvalidBlockError
+	if errors.As(err, &blockErr) {
+		httpjson.WriteErrorDetails(w, http.StatusBadRequest, err, blockErr.Errors)
+		return
+	}
+	httpjson.WriteError(w, errorStatus(err), err)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, blockchaindomain.ErrUnknownParent):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,30 @@
+package status
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/p2p/domain"
+	"go-runtime-demo/internal/app/p2p/usecase/status"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = domain.StatusPath
+
+type Handler struct {
+	useCase status.UseCase
+}
+
+func NewHandler(useCase status.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodGet)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	result := h.useCase.Execute(r.Context(), r.Header.Get(domain.NodeHeader))
+	httpjson.WriteJSON(w, http.StatusOK, result)
+}
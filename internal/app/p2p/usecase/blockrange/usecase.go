@@ -0,0 +1,34 @@
+package blockrange
+
+import (
+	"context"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/p2p/domain"
+)
+
+type (
+	UseCase struct {
+		node *domain.Node
+	}
+
+	Input struct {
+		Caller string
+		From   int
+		Limit  int
+	}
+)
+
+func New(node *domain.Node) UseCase {
+	return UseCase{
+		node: node,
+	}
+}
+
+// Execute returns the canonical blocks a syncing peer asked for
+func (uc UseCase) Execute(_ context.Context, input Input) []blockchaindomain.Block {
+	if input.Caller != "" {
+		uc.node.Discover(input.Caller)
+	}
+	return uc.node.Blocks(input.From, input.Limit)
+}
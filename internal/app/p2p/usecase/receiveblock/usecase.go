@@ -0,0 +1,23 @@
+package receiveblock
+
+import (
+	"context"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/p2p/domain"
+)
+
+type UseCase struct {
+	node *domain.Node
+}
+
+func New(node *domain.Node) UseCase {
+	return UseCase{
+		node: node,
+	}
+}
+
+// Execute validates and stores a block gossiped by sender
+func (uc UseCase) Execute(_ context.Context, sender string, block blockchaindomain.Block) (domain.Receipt, error) {
+	return uc.node.Receive(sender, block)
+}
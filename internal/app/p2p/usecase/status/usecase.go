@@ -0,0 +1,25 @@
+package status
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/p2p/domain"
+)
+
+type UseCase struct {
+	node *domain.Node
+}
+
+func New(node *domain.Node) UseCase {
+	return UseCase{
+		node: node,
+	}
+}
+
+// Execute records the calling node, when it identified itself, and describes this one
+func (uc UseCase) Execute(_ context.Context, caller string) domain.Status {
+	if caller != "" {
+		uc.node.Discover(caller)
+	}
+	return uc.node.Status()
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// linkQueueSize bounds the messages in flight on a link
+const linkQueueSize = 64
+
+// link is a one-way connection between two nodes. Its goroutine delays
+// every message by the latency plus a random jitter, drops it with the
+// configured probability and drops it when a partition separates the nodes
+// at delivery time. Messages are delivered in order.
+type link struct {
+	net   *network
+	from  int
+	to    int
+	queue chan message
+	rng   *rand.Rand
+}
+
+func newLink(net *network, from, to int) *link {
+	return &link{
+		net:   net,
+		from:  from,
+		to:    to,
+		queue: make(chan message, linkQueueSize),
+		rng:   rand.New(rand.NewPCG(net.cfg.Seed, uint64(from)<<32|uint64(to))),
+	}
+}
+
+// send queues msg without blocking; a full link drops it
+func (l *link) send(msg message) {
+	msg.sentAt = time.Now()
+	l.net.messages.sent.Add(1)
+
+	select {
+	case l.queue <- msg:
+	default:
+		l.net.messages.overflowed.Add(1)
+	}
+}
+
+func (l *link) run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		var msg message
+		select {
+		case <-ctx.Done():
+			return
+		case msg = <-l.queue:
+		}
+
+		if l.rng.Float64() < l.net.cfg.PacketLoss {
+			l.net.messages.lost.Add(1)
+			continue
+		}
+
+		delay := l.net.cfg.Latency
+		if l.net.cfg.Jitter > 0 {
+			delay += time.Duration(l.rng.Int64N(int64(l.net.cfg.Jitter)))
+		}
+		if wait := time.Until(msg.sentAt.Add(delay)); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		}
+
+		if l.net.partitioned(l.from, l.to, time.Now()) {
+			l.net.messages.partitioned.Add(1)
+			continue
+		}
+
+		select {
+		case l.net.nodes[l.to].inbox <- msg:
+			l.net.messages.delivered.Add(1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+)
+
+const (
+	DefaultNodes         = 8
+	DefaultDifficulty    = 10
+	DefaultDuration      = 5 * time.Second
+	DefaultBlockInterval = 200 * time.Millisecond
+	DefaultTipInterval   = 500 * time.Millisecond
+
+	MaxNodes    = 2000
+	MaxLinks    = 20000
+	MaxDuration = time.Minute
+	MaxLatency  = 10 * time.Second
+
+	// convergenceTimeout bounds how long the nodes are given to agree on a
+	// tip after Duration
+	convergenceTimeout = 10 * time.Second
+	sampleInterval     = 10 * time.Millisecond
+)
+
+var ErrInvalidConfig = errors.New("invalid simulation config")
+
+type (
+	// Config describes a simulated network. Durations are offsets from the
+	// start of the simulation.
+	Config struct {
+		Nodes int
+		// Miners is the number of nodes that mine, the first ones. 0 means all.
+		Miners int
+		// Degree is the number of peers of each node, on a ring lattice.
+		// 0 connects every pair of nodes.
+		Degree int
+		// Difficulty is in leading zero bits
+		Difficulty int
+		// Duration is how long the network runs before convergence is
+		// measured. Miners keep mining until the nodes agree on a tip, as the
+		// next block is what breaks a tie between branches of equal work.
+		Duration time.Duration
+		// BlockInterval is the expected time between two blocks across the
+		// whole network. Each miner waits an exponentially distributed delay
+		// so blocks arrive as a Poisson process.
+		BlockInterval time.Duration
+		// TipInterval is how often nodes re-announce their tip, which repairs
+		// lost messages and healed partitions
+		TipInterval time.Duration
+		Latency     time.Duration
+		Jitter      time.Duration
+		// PacketLoss is the probability in [0, 1) that a message is dropped
+		PacketLoss float64
+		Partitions []Partition
+		Seed       uint64
+	}
+
+	// Partition cuts the links between groups of nodes from Start to End.
+	// Nodes listed in no group form one more group.
+	Partition struct {
+		Start  time.Duration
+		End    time.Duration
+		Groups [][]int
+	}
+
+	Result struct {
+		Seed        uint64 `json:"seed"`
+		Nodes       int    `json:"nodes"`
+		Miners      int    `json:"miners"`
+		Links       int    `json:"links"`
+		Duration    string `json:"duration"`
+		BlocksMined int    `json:"blocks_mined"`
+		// Height is the height of the tip of node 0 at the end
+		Height int `json:"height"`
+		// StaleBlocks are blocks mined during the simulation that are not
+		// part of the final chain of node 0
+		StaleBlocks int     `json:"stale_blocks"`
+		ForkRate    float64 `json:"fork_rate"`
+		Reorgs      int     `json:"reorgs"`
+		// Converged reports whether every node had the same tip before the
+		// convergence timeout. ConvergenceTime is measured from the end of
+		// Duration.
+		Converged       bool     `json:"converged"`
+		ConvergenceTime string   `json:"convergence_time"`
+		DistinctTips    int      `json:"distinct_tips"`
+		InvalidBlocks   int      `json:"invalid_blocks"`
+		Messages        Messages `json:"messages"`
+		Runtime         Runtime  `json:"runtime"`
+	}
+
+	Messages struct {
+		Sent        int64 `json:"sent"`
+		Delivered   int64 `json:"delivered"`
+		Lost        int64 `json:"lost"`
+		Partitioned int64 `json:"partitioned"`
+		// Overflowed counts messages dropped because a queue was full
+		Overflowed int64 `json:"overflowed"`
+	}
+
+	// Runtime is the cost of the whole simulation for the Go runtime
+	Runtime struct {
+		GoroutinesBefore int     `json:"goroutines_before"`
+		PeakGoroutines   int     `json:"peak_goroutines"`
+		HeapBeforeMB     float64 `json:"heap_before_mb"`
+		PeakHeapMB       float64 `json:"peak_heap_mb"`
+		HeapAfterMB      float64 `json:"heap_after_mb"`
+		GCCycles         uint64  `json:"gc_cycles"`
+	}
+
+	network struct {
+		cfg      Config
+		nodes    []*simNode
+		links    int
+		start    time.Time
+		messages struct {
+			sent, delivered, lost, partitioned, overflowed atomic.Int64
+		}
+		// groups[p][i] is the group of node i during partition p
+		groups [][]int
+	}
+
+	runtimeSample struct {
+		goroutines int
+		heapBytes  uint64
+		gcCycles   uint64
+	}
+)
+
+// Validate checks the limits of the simulation
+func (c Config) Validate() error {
+	switch {
+	case c.Nodes < 2 || c.Nodes > MaxNodes:
+		return fmt.Errorf("%w: nodes must be between 2 and %d", ErrInvalidConfig, MaxNodes)
+	case c.Miners < 0 || c.Miners > c.Nodes:
+		return fmt.Errorf("%w: miners must be between 0 and the number of nodes", ErrInvalidConfig)
+	case c.Degree < 0:
+		return fmt.Errorf("%w: degree must not be negative", ErrInvalidConfig)
+	case c.Nodes*c.degree() > MaxLinks:
+		return fmt.Errorf("%w: %d links exceed the limit of %d, lower degree", ErrInvalidConfig, c.Nodes*c.degree(), MaxLinks)
+	case c.Difficulty < 1 || c.Difficulty > 24:
+		return fmt.Errorf("%w: difficulty must be between 1 and 24 bits", ErrInvalidConfig)
+	case c.Duration <= 0 || c.Duration > MaxDuration:
+		return fmt.Errorf("%w: duration must be positive and at most %s", ErrInvalidConfig, MaxDuration)
+	case c.BlockInterval <= 0 || c.TipInterval <= 0:
+		return fmt.Errorf("%w: block and tip intervals must be positive", ErrInvalidConfig)
+	case c.Latency < 0 || c.Jitter < 0 || c.Latency+c.Jitter > MaxLatency:
+		return fmt.Errorf("%w: latency plus jitter must be between 0 and %s", ErrInvalidConfig, MaxLatency)
+	case c.PacketLoss < 0 || c.PacketLoss >= 1:
+		return fmt.Errorf("%w: packet loss must be in [0, 1)", ErrInvalidConfig)
+	}
+
+	for i, p := range c.Partitions {
+		if p.Start < 0 || p.End <= p.Start || p.End > c.Duration {
+			return fmt.Errorf("%w: partition %d must start before it ends, within the duration", ErrInvalidConfig, i)
+		}
+		seen := make(map[int]bool)
+		for _, group := range p.Groups {
+			for _, node := range group {
+				if node < 0 || node >= c.Nodes || seen[node] {
+					return fmt.Errorf("%w: partition %d: node %d is out of range or in two groups", ErrInvalidConfig, i, node)
+				}
+				seen[node] = true
+			}
+		}
+	}
+	return nil
+}
+
+// degree returns the number of peers of each node
+func (c Config) degree() int {
+	if c.Degree == 0 || c.Degree >= c.Nodes-1 {
+		return c.Nodes - 1
+	}
+	// The ring lattice connects the same number of neighbours on each side
+	return c.Degree + c.Degree%2
+}
+
+func (c Config) miners() int {
+	if c.Miners == 0 {
+		return c.Nodes
+	}
+	return c.Miners
+}
+
+// Run simulates the network for cfg.Duration, then measures how long the
+// nodes take to converge on a tip. Every node is a full
+// blockchain, and every node and link is a set of goroutines, so large
+// networks put thousands of goroutines on the scheduler.
+func Run(ctx context.Context, cfg Config) (Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	before := readRuntime()
+	peak := before
+
+	net, err := newNetwork(cfg)
+	if err != nil {
+		return Result{}, err
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var wg sync.WaitGroup
+	net.start = time.Now()
+	for _, node := range net.nodes {
+		node.start(runCtx, &wg)
+	}
+
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+	measured := time.NewTimer(cfg.Duration)
+	defer measured.Stop()
+	deadline := time.NewTimer(cfg.Duration + convergenceTimeout)
+	defer deadline.Stop()
+
+	// The runtime is sampled throughout. Once Duration has elapsed, the
+	// tips are compared at every sample until they are all the same.
+	var measureStart time.Time
+	converged := false
+	for done := false; !done; {
+		select {
+		case <-ctx.Done():
+			stop()
+			wg.Wait()
+			return Result{}, ctx.Err()
+		case <-measured.C:
+			measureStart = time.Now()
+		case <-deadline.C:
+			done = true
+		case <-ticker.C:
+			peak = peak.max(readRuntime())
+			if !measureStart.IsZero() && net.distinctTips() == 1 {
+				converged, done = true, true
+			}
+		}
+	}
+	convergence := time.Since(measureStart)
+
+	stop()
+	wg.Wait()
+	after := readRuntime()
+
+	result := net.result()
+	result.Duration = time.Since(net.start).String()
+	result.Converged = converged
+	result.ConvergenceTime = convergence.String()
+	result.Runtime = Runtime{
+		GoroutinesBefore: before.goroutines,
+		PeakGoroutines:   peak.goroutines,
+		HeapBeforeMB:     toMB(before.heapBytes),
+		PeakHeapMB:       toMB(peak.heapBytes),
+		HeapAfterMB:      toMB(after.heapBytes),
+		GCCycles:         after.gcCycles - before.gcCycles,
+	}
+	return result, nil
+}
+
+// newNetwork creates the nodes, each with its own in-memory chain, and the
+// links between them
+func newNetwork(cfg Config) (*network, error) {
+	net := &network{cfg: cfg, nodes: make([]*simNode, cfg.Nodes)}
+
+	for i := range net.nodes {
+		bc, err := blockchaindomain.NewBlockchain(cfg.Difficulty,
+			blockchaindomain.WithDifficultyMode(blockchaindomain.DifficultyBits),
+		)
+		if err != nil {
+			return nil, err
+		}
+		net.nodes[i] = newSimNode(i, bc, net, i < cfg.miners())
+	}
+
+	// Ring lattice: each node links to degree/2 neighbours on each side,
+	// which is every other node for a full mesh
+	half := cfg.degree() / 2
+	for i, node := range net.nodes {
+		for d := 1; d <= half; d++ {
+			for _, j := range []int{(i + d) % cfg.Nodes, (i - d + cfg.Nodes) % cfg.Nodes} {
+				if _, ok := node.links[j]; !ok && j != i {
+					node.links[j] = newLink(net, i, j)
+					net.links++
+				}
+			}
+		}
+		// An odd number of nodes in a full mesh leaves one neighbour out
+		if cfg.degree() == cfg.Nodes-1 {
+			for j := range net.nodes {
+				if _, ok := node.links[j]; !ok && j != i {
+					node.links[j] = newLink(net, i, j)
+					net.links++
+				}
+			}
+		}
+	}
+
+	net.groups = make([][]int, len(cfg.Partitions))
+	for p, partition := range cfg.Partitions {
+		groups := make([]int, cfg.Nodes)
+		for i := range groups {
+			groups[i] = len(partition.Groups)
+		}
+		for g, members := range partition.Groups {
+			for _, node := range members {
+				groups[node] = g
+			}
+		}
+		net.groups[p] = groups
+	}
+
+	return net, nil
+}
+
+// partitioned reports whether the link between two nodes is cut at time t
+func (net *network) partitioned(from, to int, t time.Time) bool {
+	elapsed := t.Sub(net.start)
+	for p, partition := range net.cfg.Partitions {
+		if elapsed >= partition.Start && elapsed < partition.End && net.groups[p][from] != net.groups[p][to] {
+			return true
+		}
+	}
+	return false
+}
+
+func (net *network) distinctTips() int {
+	tips := make(map[blockchaindomain.Hash]bool)
+	for _, node := range net.nodes {
+		tips[node.bc.Tip().Hash] = true
+	}
+	return len(tips)
+}
+
+func (net *network) result() Result {
+	result := Result{
+		Seed:         net.cfg.Seed,
+		Nodes:        net.cfg.Nodes,
+		Miners:       net.cfg.miners(),
+		Links:        net.links,
+		Height:       net.nodes[0].bc.Tip().Index,
+		DistinctTips: net.distinctTips(),
+		Messages: Messages{
+			Sent:        net.messages.sent.Load(),
+			Delivered:   net.messages.delivered.Load(),
+			Lost:        net.messages.lost.Load(),
+			Partitioned: net.messages.partitioned.Load(),
+			Overflowed:  net.messages.overflowed.Load(),
+		},
+	}
+
+	for _, node := range net.nodes {
+		result.BlocksMined += node.mined
+		result.InvalidBlocks += node.invalid
+		result.Reorgs += node.bc.Info().Reorgs
+	}
+	result.StaleBlocks = max(result.BlocksMined-result.Height, 0)
+	if result.BlocksMined > 0 {
+		result.ForkRate = float64(result.StaleBlocks) / float64(result.BlocksMined)
+	}
+	return result
+}
+
+func readRuntime() runtimeSample {
+	sample := []metrics.Sample{
+		{Name: "/sched/goroutines:goroutines"},
+		{Name: "/memory/classes/heap/objects:bytes"},
+		{Name: "/gc/cycles/total:gc-cycles"},
+	}
+	metrics.Read(sample)
+
+	return runtimeSample{
+		goroutines: int(sample[0].Value.Uint64()),
+		heapBytes:  sample[1].Value.Uint64(),
+		gcCycles:   sample[2].Value.Uint64(),
+	}
+}
+
+func (s runtimeSample) max(other runtimeSample) runtimeSample {
+	s.goroutines = max(s.goroutines, other.goroutines)
+	s.heapBytes = max(s.heapBytes, other.heapBytes)
+	return s
+}
+
+func toMB(bytes uint64) float64 {
+	return float64(bytes) / 1024 / 1024
+}
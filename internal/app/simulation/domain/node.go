@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+)
+
+const (
+	inboxSize  = 256
+	outboxSize = 256
+	// orphanLimit bounds the blocks a node keeps while it fetches their parents
+	orphanLimit = 1000
+)
+
+const (
+	// messageBlock carries a new block or a tip announcement
+	messageBlock messageKind = iota
+	// messageGetBlock asks the receiver for the block with the given hash
+	messageGetBlock
+)
+
+type (
+	messageKind int
+
+	message struct {
+		kind   messageKind
+		from   int
+		block  *blockchaindomain.Block
+		hash   blockchaindomain.Hash
+		sentAt time.Time
+	}
+
+	// simNode is one node of the simulated network. It runs a receive
+	// goroutine, a relay goroutine and, for miners, a mining goroutine.
+	simNode struct {
+		id     int
+		bc     *blockchaindomain.Blockchain
+		net    *network
+		miner  bool
+		links  map[int]*link
+		inbox  chan message
+		outbox chan *blockchaindomain.Block
+		// orphans holds blocks by the hash of their missing parent. Only the
+		// receive goroutine uses it.
+		orphans     map[blockchaindomain.Hash][]blockchaindomain.Block
+		orphanCount int
+		// Written by the node goroutines and read once they are done
+		mined   int
+		invalid int
+	}
+)
+
+func newSimNode(id int, bc *blockchaindomain.Blockchain, net *network, miner bool) *simNode {
+	node := &simNode{
+		id:      id,
+		bc:      bc,
+		net:     net,
+		miner:   miner,
+		links:   make(map[int]*link),
+		inbox:   make(chan message, inboxSize),
+		outbox:  make(chan *blockchaindomain.Block, outboxSize),
+		orphans: make(map[blockchaindomain.Hash][]blockchaindomain.Block),
+	}
+	bc.OnBlock(node.enqueue)
+	return node
+}
+
+// start runs the node and link goroutines until ctx is done
+func (n *simNode) start(ctx context.Context, wg *sync.WaitGroup) {
+	for _, l := range n.links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.run(ctx)
+		}()
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n.receive(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		n.relay(ctx)
+	}()
+
+	if n.miner {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.mine(ctx)
+		}()
+	}
+}
+
+// enqueue is the block listener. It runs with the chain lock held, so a
+// full outbox drops the block; the periodic tip announcement makes up for it.
+func (n *simNode) enqueue(block blockchaindomain.Block) {
+	select {
+	case n.outbox <- &block:
+	default:
+		n.net.messages.overflowed.Add(1)
+	}
+}
+
+// mine adds blocks after exponentially distributed delays whose mean is the
+// network block interval times the number of miners
+func (n *simNode) mine(ctx context.Context) {
+	rng := rand.New(rand.NewPCG(n.net.cfg.Seed, uint64(n.id)))
+	mean := float64(n.net.cfg.BlockInterval) * float64(n.net.cfg.miners())
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for {
+		timer.Reset(time.Duration(rng.ExpFloat64() * mean))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		payload := blockchaindomain.Payload{Data: fmt.Sprintf("node %d block %d", n.id, n.mined)}
+		if _, _, err := n.bc.AddBlock(ctx, payload); err != nil {
+			return
+		}
+		n.mined++
+	}
+}
+
+// relay sends new blocks to every peer and periodically announces the tip
+func (n *simNode) relay(ctx context.Context) {
+	ticker := time.NewTicker(n.net.cfg.TipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case block := <-n.outbox:
+			n.broadcast(block)
+		case <-ticker.C:
+			tip := n.bc.Tip()
+			n.broadcast(&tip)
+		}
+	}
+}
+
+// broadcast shares one copy of block between every message, which the
+// receivers only read
+func (n *simNode) broadcast(block *blockchaindomain.Block) {
+	for _, l := range n.links {
+		l.send(message{kind: messageBlock, from: n.id, block: block})
+	}
+}
+
+func (n *simNode) receive(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-n.inbox:
+			switch msg.kind {
+			case messageBlock:
+				n.handleBlock(msg.from, *msg.block)
+			case messageGetBlock:
+				if block, err := n.bc.BlockByHash(msg.hash); err == nil {
+					n.links[msg.from].send(message{kind: messageBlock, from: n.id, block: &block})
+				}
+			}
+		}
+	}
+}
+
+// handleBlock submits a block received from a peer. A block whose parent is
+// unknown is kept as an orphan while the parent is requested from the peer.
+func (n *simNode) handleBlock(from int, block blockchaindomain.Block) {
+	_, err := n.bc.SubmitBlock(block)
+	switch {
+	case err == nil:
+		n.connectOrphans(block.Hash)
+	case errors.Is(err, blockchaindomain.ErrBlockExists):
+	case errors.Is(err, blockchaindomain.ErrUnknownParent):
+		if n.orphanCount < orphanLimit {
+			n.orphans[block.PreviousHash] = append(n.orphans[block.PreviousHash], block)
+			n.orphanCount++
+		}
+		n.links[from].send(message{kind: messageGetBlock, from: n.id, hash: block.PreviousHash})
+	default:
+		n.invalid++
+	}
+}
+
+// connectOrphans submits the orphans waiting for hash, then theirs
+func (n *simNode) connectOrphans(hash blockchaindomain.Hash) {
+	pending := []blockchaindomain.Hash{hash}
+	for len(pending) > 0 {
+		parent := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, orphan := range n.orphans[parent] {
+			n.orphanCount--
+			_, err := n.bc.SubmitBlock(orphan)
+			if err == nil {
+				pending = append(pending, orphan.Hash)
+			} else if !errors.Is(err, blockchaindomain.ErrBlockExists) {
+				n.invalid++
+			}
+		}
+		delete(n.orphans, parent)
+	}
+}
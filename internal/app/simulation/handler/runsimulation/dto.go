@@ -0,0 +1,24 @@
+package runsimulation
+
+type (
+	InputPayload struct {
+		Nodes           int                `json:"nodes"`             // default 8
+		Miners          int                `json:"miners"`            // default: every node
+		Degree          int                `json:"degree"`            // peers per node, default: full mesh
+		Difficulty      int                `json:"difficulty"`        // leading zero bits, default 10
+		DurationMs      int                `json:"duration_ms"`       // mining time, default 5000
+		BlockIntervalMs int                `json:"block_interval_ms"` // default 200
+		TipIntervalMs   int                `json:"tip_interval_ms"`   // default 500
+		LatencyMs       int                `json:"latency_ms"`
+		JitterMs        int                `json:"jitter_ms"`
+		PacketLoss      float64            `json:"packet_loss"`
+		Partitions      []PartitionPayload `json:"partitions"`
+		Seed            uint64             `json:"seed"` // optional, random by default
+	}
+
+	PartitionPayload struct {
+		StartMs int     `json:"start_ms"`
+		EndMs   int     `json:"end_ms"`
+		Groups  [][]int `json:"groups"`
+	}
+)
@@ -0,0 +1,102 @@
+package runsimulation
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go-runtime-demo/internal/app/simulation/domain"
+	"go-runtime-demo/internal/app/simulation/usecase/runsimulation"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/simulations"
+
+type Handler struct {
+	useCase runsimulation.UseCase
+}
+
+func NewHandler(useCase runsimulation.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	var payload InputPayload
+	if err := httpjson.ReadJSON(r, &payload); err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := h.useCase.Execute(r.Context(), payload.config())
+	if err != nil {
+		httpjson.WriteError(w, errorStatus(err), err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, result)
+}
+
+// config converts the payload, filling in the defaults
+func (p InputPayload) config() domain.Config {
+	cfg := domain.Config{
+		Nodes:         p.Nodes,
+		Miners:        p.Miners,
+		Degree:        p.Degree,
+		Difficulty:    p.Difficulty,
+		Duration:      milliseconds(p.DurationMs),
+		BlockInterval: milliseconds(p.BlockIntervalMs),
+		TipInterval:   milliseconds(p.TipIntervalMs),
+		Latency:       milliseconds(p.LatencyMs),
+		Jitter:        milliseconds(p.JitterMs),
+		PacketLoss:    p.PacketLoss,
+		Seed:          p.Seed,
+	}
+
+	if cfg.Nodes == 0 {
+		cfg.Nodes = domain.DefaultNodes
+	}
+	if cfg.Difficulty == 0 {
+		cfg.Difficulty = domain.DefaultDifficulty
+	}
+	if cfg.Duration == 0 {
+		cfg.Duration = domain.DefaultDuration
+	}
+	if cfg.BlockInterval == 0 {
+		cfg.BlockInterval = domain.DefaultBlockInterval
+	}
+	if cfg.TipInterval == 0 {
+		cfg.TipInterval = domain.DefaultTipInterval
+	}
+
+	for _, partition := range p.Partitions {
+		cfg.Partitions = append(cfg.Partitions, domain.Partition{
+			Start:  milliseconds(partition.StartMs),
+			End:    milliseconds(partition.EndMs),
+			Groups: partition.Groups,
+		})
+	}
+
+	return cfg
+}
+
+func milliseconds(ms int) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidConfig):
+		return http.StatusBadRequest
+	case errors.Is(err, context.Canceled):
+		return httpjson.StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,23 @@
+package runsimulation
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"go-runtime-demo/internal/app/simulation/domain"
+)
+
+type UseCase struct{}
+
+func New() UseCase {
+	return UseCase{}
+}
+
+// Execute runs the simulation to completion. A zero seed picks a random one,
+// reported in the result so the run can be repeated.
+func (uc UseCase) Execute(ctx context.Context, cfg domain.Config) (domain.Result, error) {
+	if cfg.Seed == 0 {
+		cfg.Seed = rand.Uint64()
+	}
+	return domain.Run(ctx, cfg)
+}
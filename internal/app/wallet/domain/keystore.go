@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+)
+
+var ErrWalletNotFound = errors.New("wallet not found")
+
+type (
+	// Wallet is an ed25519 key pair identified by the address derived from
+	// its public key. The private key never leaves the keystore.
+	Wallet struct {
+		Address    string    `json:"address"`
+		PublicKey  string    `json:"public_key"`
+		CreatedAt  time.Time `json:"created_at"`
+		privateKey ed25519.PrivateKey
+	}
+
+	// Keystore holds the wallets created by the server in memory. It is a
+	// custodial demo: keys are lost on restart.
+	Keystore struct {
+		wallets map[string]Wallet
+		mu      sync.RWMutex
+	}
+)
+
+func NewKeystore() *Keystore {
+	return &Keystore{
+		wallets: make(map[string]Wallet),
+	}
+}
+
+// Create generates a key pair and stores the wallet under its address
+func (ks *Keystore) Create() (Wallet, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Wallet{}, fmt.Errorf("generating key pair: %w", err)
+	}
+
+	wallet := Wallet{
+		Address:    blockchaindomain.AddressFromPublicKey(publicKey),
+		PublicKey:  hex.EncodeToString(publicKey),
+		CreatedAt:  time.Now().Round(0),
+		privateKey: privateKey,
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.wallets[wallet.Address] = wallet
+
+	return wallet, nil
+}
+
+// Get returns the wallet stored under address
+func (ks *Keystore) Get(address string) (Wallet, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	wallet, ok := ks.wallets[address]
+	if !ok {
+		return Wallet{}, fmt.Errorf("%w: %s", ErrWalletNotFound, address)
+	}
+	return wallet, nil
+}
+
+// Sign returns tx signed by the wallet stored under address
+func (ks *Keystore) Sign(address string, tx blockchaindomain.Transaction) (blockchaindomain.Transaction, error) {
+	wallet, err := ks.Get(address)
+	if err != nil {
+		return blockchaindomain.Transaction{}, err
+	}
+	return blockchaindomain.SignTransaction(tx, wallet.privateKey), nil
+}
@@ -0,0 +1,34 @@
+package createwallet
+
+import (
+	"net/http"
+
+	"go-runtime-demo/internal/app/wallet/usecase/createwallet"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/wallets"
+
+type Handler struct {
+	useCase createwallet.UseCase
+}
+
+func NewHandler(useCase createwallet.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	wallet, err := h.useCase.Execute(r.Context())
+	if err != nil {
+		httpjson.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusCreated, wallet)
+}
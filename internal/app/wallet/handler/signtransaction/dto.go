@@ -0,0 +1,12 @@
+package signtransaction
+
+import "time"
+
+type InputPayload struct {
+	Payload   string    `json:"payload"`
+	Timestamp time.Time `json:"timestamp"` // optional, defaults to the time of the request
+	To        string    `json:"to"`        // transfers only
+	Amount    uint64    `json:"amount"`    // transfers only
+	Nonce     *uint64   `json:"nonce"`     // optional, defaults to the account's next nonce
+	Fee       uint64    `json:"fee"`       // transfers only, paid to the miner
+}
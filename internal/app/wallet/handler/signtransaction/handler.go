@@ -0,0 +1,63 @@
+package signtransaction
+
+import (
+	"errors"
+	"net/http"
+
+	"go-runtime-demo/internal/app/wallet/domain"
+	"go-runtime-demo/internal/app/wallet/usecase/signtransaction"
+	httpjson "go-runtime-demo/pkg/http"
+
+	"github.com/gorilla/mux"
+)
+
+const Path = "/wallets/{address}/sign"
+
+type Handler struct {
+	useCase signtransaction.UseCase
+}
+
+func NewHandler(useCase signtransaction.UseCase) Handler {
+	return Handler{useCase: useCase}
+}
+
+func RegisterEndpoint(r *mux.Router, h Handler) {
+	r.HandleFunc(Path, h.Handle).Methods(http.MethodPost)
+}
+
+func (h Handler) Handle(w http.ResponseWriter, r *http.Request) {
+	var payload InputPayload
+	if err := httpjson.ReadJSON(r, &payload); err != nil {
+		httpjson.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if payload.Payload == "" {
+		httpjson.WriteError(w, http.StatusBadRequest, httpjson.ErrMissingValue)
+		return
+	}
+
+	tx, err := h.useCase.Execute(r.Context(), mux.Vars(r)["address"], signtransaction.Input{
+		Payload:   payload.Payload,
+		Timestamp: payload.Timestamp,
+		To:        payload.To,
+		Amount:    payload.Amount,
+		Nonce:     payload.Nonce,
+		Fee:       payload.Fee,
+	})
+	if err != nil {
+		httpjson.WriteError(w, errorStatus(err), err)
+		return
+	}
+
+	httpjson.WriteJSON(w, http.StatusOK, tx)
+}
+
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrWalletNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,21 @@
+package createwallet
+
+import (
+	"context"
+
+	"go-runtime-demo/internal/app/wallet/domain"
+)
+
+type UseCase struct {
+	keystore *domain.Keystore
+}
+
+func New(keystore *domain.Keystore) UseCase {
+	return UseCase{
+		keystore: keystore,
+	}
+}
+
+func (uc UseCase) Execute(_ context.Context) (domain.Wallet, error) {
+	return uc.keystore.Create()
+}
@@ -0,0 +1,60 @@
+package signtransaction
+
+import (
+	"context"
+	"time"
+
+	blockchaindomain "go-runtime-demo/internal/app/blockchain/domain"
+	"go-runtime-demo/internal/app/wallet/domain"
+)
+
+type (
+	UseCase struct {
+		keystore   *domain.Keystore
+		blockchain *blockchaindomain.Blockchain
+	}
+
+	// Input describes the transaction to sign. Set To and Amount for a
+	// transfer; Nonce defaults to the next nonce of the wallet account,
+	// counting its pending transfers.
+	Input struct {
+		Payload   string    `json:"payload"`
+		Timestamp time.Time `json:"timestamp"`
+		To        string    `json:"to"`
+		Amount    uint64    `json:"amount"`
+		Nonce     *uint64   `json:"nonce"`
+		Fee       uint64    `json:"fee"`
+	}
+)
+
+func New(keystore *domain.Keystore, blockchain *blockchaindomain.Blockchain) UseCase {
+	return UseCase{
+		keystore:   keystore,
+		blockchain: blockchain,
+	}
+}
+
+// Execute signs a transaction with the wallet stored under address. The
+// result can be submitted as is to POST /transactions.
+func (uc UseCase) Execute(_ context.Context, address string, input Input) (blockchaindomain.Transaction, error) {
+	if input.Timestamp.IsZero() {
+		input.Timestamp = time.Now()
+	}
+
+	tx := blockchaindomain.Transaction{
+		Payload:   input.Payload,
+		Timestamp: input.Timestamp,
+		To:        input.To,
+		Amount:    input.Amount,
+		Fee:       input.Fee,
+	}
+	if tx.IsTransfer() {
+		if input.Nonce != nil {
+			tx.Nonce = *input.Nonce
+		} else {
+			tx.Nonce = uc.blockchain.NextNonce(address)
+		}
+	}
+
+	return uc.keystore.Sign(address, tx)
+}
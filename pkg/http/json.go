@@ -3,11 +3,27 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"iter"
+	"mime"
 	"net/http"
+	"strings"
+)
+
+const (
+	// StatusClientClosedRequest is the non-standard status (popularised by nginx)
+	// recorded when the client disconnects before the response is written
+	StatusClientClosedRequest = 499
+
+	// ContentTypeNDJSON is newline-delimited JSON, one document per line
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// ndjsonFlushEvery is the number of documents written between two flushes
+	ndjsonFlushEvery = 100
 )
 
 var (
 	ErrMissingValue = errors.New("missing required value")
+	ErrInvalidValue = errors.New("invalid value")
 )
 
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) {
@@ -20,6 +36,49 @@ func WriteError(w http.ResponseWriter, status int, err error) {
 	WriteJSON(w, status, map[string]string{"error": err.Error()})
 }
 
+// WriteErrorDetails writes the error message along with a structured
+// description of the failure clients can act on
+func WriteErrorDetails(w http.ResponseWriter, status int, err error, details interface{}) {
+	WriteJSON(w, status, map[string]interface{}{"error": err.Error(), "details": details})
+}
+
 func ReadJSON(r *http.Request, v interface{}) error {
 	return json.NewDecoder(r.Body).Decode(v)
 }
+
+// Accepts reports whether the Accept header of r lists mediaType
+func Accepts(r *http.Request, mediaType string) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for part := range strings.SplitSeq(value, ",") {
+			if accepted, _, err := mime.ParseMediaType(part); err == nil && accepted == mediaType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WriteNDJSON streams the documents of seq one per line, flushing every
+// ndjsonFlushEvery documents so clients can consume them as they arrive.
+// It stops at the first write error, usually a client that went away; the
+// status has been sent by then, so the error can only be logged.
+func WriteNDJSON[T any](w http.ResponseWriter, status int, seq iter.Seq[T]) error {
+	w.Header().Set("Content-Type", ContentTypeNDJSON)
+	w.WriteHeader(status)
+
+	rc := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	written := 0
+	for v := range seq {
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		written++
+		if written%ndjsonFlushEvery == 0 {
+			if err := rc.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return rc.Flush()
+}
@@ -1,22 +1,42 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
+// ErrServerShutdown is the cause of the request contexts cancelled by
+// Shutdown. It wraps context.Canceled, so handlers that stop on cancellation
+// stop on shutdown too.
+var ErrServerShutdown = fmt.Errorf("server shutting down: %w", context.Canceled)
+
 type Server struct {
-	router *mux.Router
-	port   string
+	router     *mux.Router
+	port       string
+	httpServer *http.Server
+	cancel     context.CancelCauseFunc
 }
 
 func NewServer(port string) *Server {
+	router := mux.NewRouter()
+	ctx, cancel := context.WithCancelCause(context.Background())
+
 	return &Server{
-		router: mux.NewRouter(),
+		router: router,
 		port:   port,
+		httpServer: &http.Server{
+			Addr:    fmt.Sprintf(":%s", port),
+			Handler: router,
+			// Every request context derives from ctx, so Shutdown can cancel them
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		},
+		cancel: cancel,
 	}
 }
 
@@ -24,8 +44,25 @@ func (s *Server) Router() *mux.Router {
 	return s.router
 }
 
+// Start blocks until the server stops. A graceful Shutdown is not reported as an error.
 func (s *Server) Start() error {
-	addr := fmt.Sprintf(":%s", s.port)
-	log.Printf("Server starting on %s", addr)
-	return http.ListenAndServe(addr, s.router)
+	log.Printf("Server starting on %s", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// OnShutdown registers fn to run when Shutdown is called, so long-lived
+// handlers such as event streams can return instead of holding it up
+func (s *Server) OnShutdown(fn func()) {
+	s.httpServer.RegisterOnShutdown(fn)
+}
+
+// Shutdown cancels the context of every in-flight request with
+// ErrServerShutdown, so long computations such as mining return, then waits
+// for the handlers to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel(ErrServerShutdown)
+	return s.httpServer.Shutdown(ctx)
 }
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ContentTypeEventStream is the media type of server-sent events
+const ContentTypeEventStream = "text/event-stream"
+
+// EventStream writes server-sent events, flushing each one so it reaches the
+// client as soon as it is sent
+type EventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// NewEventStream sends the status and headers of an event stream
+func NewEventStream(w http.ResponseWriter) (*EventStream, error) {
+	w.Header().Set("Content-Type", ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Ask buffering proxies such as nginx to pass events through as they come
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &EventStream{w: w, rc: http.NewResponseController(w)}
+	return s, s.rc.Flush()
+}
+
+// Send writes one event whose data is the JSON encoding of data. Empty id
+// and event fields are left out.
+func (s *EventStream) Send(id, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(s.w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(s.w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", payload); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Comment writes a comment line, which clients ignore. It keeps idle
+// connections from being closed by proxies.
+func (s *EventStream) Comment(text string) error {
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}